@@ -0,0 +1,109 @@
+package alpm
+
+import (
+	"maps"
+	"slices"
+)
+
+// DefaultDBPath is the default location
+// of the pacman databases.
+const DefaultDBPath = "/var/lib/pacman"
+
+// DefaultConfPath is the default location
+// of the pacman configuration.
+const DefaultConfPath = "/etc/pacman.conf"
+
+// Database is an in-memory ALPM database.
+type Database struct {
+	Name      string
+	Packages  map[string]*Package
+	providers map[string][]*Package
+}
+
+// NewDatabase returns an empty database.
+func NewDatabase(name string) *Database {
+	return &Database{
+		Name:      name,
+		Packages:  make(map[string]*Package),
+		providers: make(map[string][]*Package),
+	}
+}
+
+// Add adds the packages to the database.
+func (db *Database) Add(packages ...*Package) {
+	for _, p := range packages {
+		if p.Name == "" {
+			continue
+		}
+		p.Repo = db.Name
+		db.Packages[p.Name] = p
+		for _, e := range p.Provides {
+			n := DependName(e)
+			db.providers[n] = append(db.providers[n], p)
+		}
+	}
+}
+
+// Get returns the package with the given name.
+func (db *Database) Get(name string) (p *Package, ok bool) {
+	p, ok = db.Packages[name]
+	return
+}
+
+// Providers returns the packages which are named
+// like the depend or which provide it.
+func (db *Database) Providers(depend string) (providers []*Package) {
+	name := DependName(depend)
+	if p, ok := db.Packages[name]; ok {
+		providers = append(providers, p)
+	}
+	for _, p := range db.providers[name] {
+		if p.Name != name {
+			providers = append(providers, p)
+		}
+	}
+	return
+}
+
+// Satisfies checks if a package of the database satisfies the depend
+// (see Package.Satisfies).
+func (db *Database) Satisfies(depend string) bool {
+	return slices.ContainsFunc(db.Providers(depend), func(p *Package) bool {
+		return p.Satisfies(depend)
+	})
+}
+
+// Names returns the sorted names of all packages of the database.
+func (db *Database) Names() []string {
+	return slices.Sorted(maps.Keys(db.Packages))
+}
+
+// Databases is a list of databases,
+// in the order of priority.
+type Databases []*Database
+
+// Get returns the package with the given name
+// in the first database which contains it.
+func (dbs Databases) Get(name string) (p *Package, ok bool) {
+	for _, db := range dbs {
+		if p, ok = db.Get(name); ok {
+			return
+		}
+	}
+	return
+}
+
+// Providers returns the packages of all databases
+// which are named like the depend or which provide it.
+func (dbs Databases) Providers(depend string) (providers []*Package) {
+	for _, db := range dbs {
+		providers = append(providers, db.Providers(depend)...)
+	}
+	return
+}
+
+// Contains checks if a package of a database
+// is named like the depend or provides it.
+func (dbs Databases) Contains(depend string) bool {
+	return len(dbs.Providers(depend)) > 0
+}
@@ -0,0 +1,86 @@
+package alpm
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys of the desc files.
+const (
+	keyName        = "NAME"
+	keyVersion     = "VERSION"
+	keyBase        = "BASE"
+	keyDescription = "DESC"
+	keyArch        = "ARCH"
+	keyUrl         = "URL"
+	keyLicense     = "LICENSE"
+	keyProvides    = "PROVIDES"
+	keyDepends     = "DEPENDS"
+	keyMakeDepends = "MAKEDEPENDS"
+	keyOptDepends  = "OPTDEPENDS"
+	keyConflicts   = "CONFLICTS"
+	keyReplaces    = "REPLACES"
+	keyReason      = "REASON"
+	keyInstallDate = "INSTALLDATE"
+)
+
+// parseDesc parses a desc file (or a depends file)
+// and adds the read sections to entries.
+// Each section begins with a %KEY% line, followed by
+// its values (one per line), and ends with a blank line.
+func parseDesc(r io.Reader, entries map[string][]string) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var key string
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+			key = ""
+		case key == "" && len(line) > 2 && line[0] == '%' && line[len(line)-1] == '%':
+			key = line[1 : len(line)-1]
+			if _, ok := entries[key]; !ok {
+				entries[key] = []string{}
+			}
+		case key != "":
+			entries[key] = append(entries[key], line)
+		}
+	}
+	return sc.Err()
+}
+
+func first(entries map[string][]string, key string) string {
+	if v := entries[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// newPackage returns a package from the parsed sections of a desc file.
+func newPackage(entries map[string][]string) *Package {
+	p := &Package{
+		Name:        first(entries, keyName),
+		Version:     first(entries, keyVersion),
+		Base:        first(entries, keyBase),
+		Description: first(entries, keyDescription),
+		Arch:        first(entries, keyArch),
+		Url:         first(entries, keyUrl),
+		Licenses:    entries[keyLicense],
+		Provides:    entries[keyProvides],
+		Depends:     entries[keyDepends],
+		MakeDepends: entries[keyMakeDepends],
+		OptDepends:  entries[keyOptDepends],
+		Conflicts:   entries[keyConflicts],
+		Replaces:    entries[keyReplaces],
+	}
+	if reason, err := strconv.Atoi(first(entries, keyReason)); err == nil {
+		p.Reason = InstallReason(reason)
+	}
+	if date, err := strconv.ParseInt(first(entries, keyInstallDate), 10, 64); err == nil {
+		p.InstallDate = time.Unix(date, 0)
+	}
+	return p
+}
@@ -0,0 +1,48 @@
+package alpm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocalDatabaseName is the name of the database
+// of the installed packages.
+const LocalDatabaseName = "local"
+
+// LoadLocalDatabase reads the database of the installed packages
+// in the local subdirectory of the given pacman database path.
+// Each installed package is described by a desc file
+// in its own directory.
+func LoadLocalDatabase(dbPath string) (db *Database, err error) {
+	localDir := filepath.Join(dbPath, LocalDatabaseName)
+	var dirs []os.DirEntry
+	if dirs, err = os.ReadDir(localDir); err != nil {
+		return
+	}
+
+	db = NewDatabase(LocalDatabaseName)
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		fpath := filepath.Join(localDir, dir.Name(), "desc")
+		var f *os.File
+		if f, err = os.Open(fpath); err != nil {
+			if os.IsNotExist(err) {
+				err = nil
+				continue
+			}
+			return nil, err
+		}
+		entries := make(map[string][]string)
+		err = parseDesc(f, entries)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf(errInvalidDesc, fpath)
+		}
+		db.Add(newPackage(entries))
+	}
+
+	return
+}
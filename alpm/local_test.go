@@ -0,0 +1,57 @@
+package alpm
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadLocalDatabase(t *testing.T) {
+	db, err := LoadLocalDatabase("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Directories without desc file are ignored.
+	if names := db.Names(); !slices.Equal(names, []string{"foo", "noversion"}) {
+		t.Fatalf("db.Names() = %v", names)
+	}
+
+	foo, _ := db.Get("foo")
+	if foo.Version != "1.2-1" || foo.Description != "A foo package" || foo.Url != "https://example.org/foo" {
+		t.Errorf("bad foo: %+v", foo)
+	}
+	if !slices.Equal(foo.Licenses, []string{"GPL-2.0-or-later", "LGPL-2.1-or-later"}) {
+		t.Errorf("foo.Licenses = %v", foo.Licenses)
+	}
+	if !slices.Equal(foo.OptDepends, []string{"bar: for the bar support"}) {
+		t.Errorf("foo.OptDepends = %v", foo.OptDepends)
+	}
+	if foo.Reason != ReasonDepend || !foo.InstallDate.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("bad reason or install date: %+v", foo)
+	}
+
+	// A package without %VERSION% is kept with an empty version.
+	p, ok := db.Get("noversion")
+	if !ok || p.Version != "" {
+		t.Errorf("bad noversion: %+v", p)
+	}
+
+	for _, tt := range []struct {
+		depend string
+		want   bool
+	}{
+		{"foo", true},
+		{"foo>=1.2", true},
+		{"foo<1.2", false},
+		{"zlib", false},
+		{"libfoo.so", true},
+		{"libfoo.so=1-64", true},
+		{"foo-bin", true},
+		{"foo-bin>=1", false},
+		{"noversion>=1", false},
+	} {
+		if got := db.Satisfies(tt.depend); got != tt.want {
+			t.Errorf("db.Satisfies(%q) = %v, want %v", tt.depend, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package alpm
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// InstallReason is the reason why a package is installed.
+type InstallReason int
+
+const (
+	ReasonExplicit InstallReason = iota
+	ReasonDepend
+)
+
+// Package represents an entry of an ALPM database.
+type Package struct {
+	Name        string
+	Version     string
+	Base        string
+	Description string
+	Arch        string
+	Url         string
+	Licenses    []string
+	Provides    []string
+	Depends     []string
+	MakeDepends []string
+	OptDepends  []string
+	Conflicts   []string
+	Replaces    []string
+	Repo        string
+	Reason      InstallReason
+	InstallDate time.Time
+}
+
+// DependName returns the name of a depend, without
+// the version constraint or the description.
+func DependName(depend string) string {
+	for _, s := range []string{">", "<", "=", ":"} {
+		if i := strings.Index(depend, s); i > 0 {
+			depend = depend[:i]
+		}
+	}
+	return strings.TrimSpace(depend)
+}
+
+// IsProvider checks if the package is named name
+// or declares it in its provides.
+func (p *Package) IsProvider(name string) bool {
+	return p.Name == name || slices.ContainsFunc(p.Provides, func(e string) bool {
+		return DependName(e) == name
+	})
+}
+
+// ParseDepend splits a depend to its name, its comparison
+// operator (<, <=, =, >= or >) and its version.
+// If the depend has no version constraint, op and version are empty.
+func ParseDepend(depend string) (name, op, version string) {
+	i := strings.IndexAny(depend, "<>=")
+	if i <= 0 {
+		return DependName(depend), "", ""
+	}
+	name, op, version = strings.TrimSpace(depend[:i]), depend[i:i+1], depend[i+1:]
+	if op != "=" && strings.HasPrefix(version, "=") {
+		op, version = op+"=", version[1:]
+	}
+	version = strings.TrimSpace(version)
+	return
+}
+
+// satisfies checks if the version matches the constraint op/want.
+func satisfies(version, op, want string) bool {
+	c := VerCmp(version, want)
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case "=":
+		return c == 0
+	case ">=":
+		return c >= 0
+	case ">":
+		return c > 0
+	}
+	return true
+}
+
+// Satisfies checks if the package satisfies the depend,
+// by its name and its version or by one of its provides.
+// Like pacman, a provide without version only satisfies
+// the depends without version constraint.
+func (p *Package) Satisfies(depend string) bool {
+	name, op, want := ParseDepend(depend)
+	if p.Name == name && (op == "" || satisfies(p.Version, op, want)) {
+		return true
+	}
+	for _, e := range p.Provides {
+		pname, pop, pversion := ParseDepend(e)
+		if pname != name {
+			continue
+		}
+		if op == "" || (pop == "=" && satisfies(pversion, op, want)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package alpm
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompress detects the compression of the stream
+// and returns the decompressed stream.
+func decompress(r io.Reader, name string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(6)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, magicBzip2):
+		return bzip2.NewReader(br), nil
+	case bytes.HasPrefix(magic, magicZstd):
+		d, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, magicXz):
+		return xz.NewReader(br)
+	case len(magic) == 0:
+		return br, nil
+	}
+	// Uncompressed tar: the header is checked by the tar reader.
+	if header, err := br.Peek(262); err == nil && string(header[257:262]) == "ustar" {
+		return br, nil
+	}
+	return nil, fmt.Errorf(errUnknownCompression, name)
+}
+
+// ReadSyncDatabase reads a sync database from the given reader.
+// The content must be a tar archive (optionally compressed using
+// gzip, bzip2, xz or zstd) of directories containing a desc file
+// and optionally a depends file.
+func ReadSyncDatabase(r io.Reader, name string) (db *Database, err error) {
+	if r, err = decompress(r, name); err != nil {
+		return
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	entries := make(map[string]map[string][]string)
+	var dirs []string
+	tr := tar.NewReader(r)
+	for {
+		var header *tar.Header
+		if header, err = tr.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+				break
+			}
+			return
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		dir, file := filepath.Split(header.Name)
+		if file != "desc" && file != "depends" {
+			continue
+		}
+		e, ok := entries[dir]
+		if !ok {
+			e = make(map[string][]string)
+			entries[dir], dirs = e, append(dirs, dir)
+		}
+		if err = parseDesc(tr, e); err != nil {
+			return nil, fmt.Errorf(errInvalidDesc, header.Name)
+		}
+	}
+
+	db = NewDatabase(name)
+	for _, dir := range dirs {
+		db.Add(newPackage(entries[dir]))
+	}
+
+	return
+}
+
+// LoadSyncDatabase reads the sync database at the given path.
+// The name of the database is the name of the file without extension.
+func LoadSyncDatabase(fpath string) (db *Database, err error) {
+	var f *os.File
+	if f, err = os.Open(fpath); err != nil {
+		return
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(fpath), ".db")
+	return ReadSyncDatabase(f, name)
+}
+
+// ReadRepos returns the names of the repos declared in the given
+// pacman configuration, in order of declaration (which is the order
+// used by pacman to search the packages).
+func ReadRepos(r io.Reader) (repos []string, err error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+		if name := strings.TrimSpace(line[1 : len(line)-1]); name != "options" && !slices.Contains(repos, name) {
+			repos = append(repos, name)
+		}
+	}
+	return repos, sc.Err()
+}
+
+// LoadRepos returns the names of the repos declared
+// in the pacman configuration at the given path.
+func LoadRepos(fpath string) (repos []string, err error) {
+	var f *os.File
+	if f, err = os.Open(fpath); err != nil {
+		return
+	}
+	defer f.Close()
+
+	return ReadRepos(f)
+}
+
+// LoadSyncDatabases reads all sync databases (*.db files)
+// of the sync directory of the given pacman database path.
+// If repos are given, only these repos are loaded, in this order.
+// Otherwise, all repos are loaded in alphabetical order.
+func LoadSyncDatabases(dbPath string, repos ...string) (dbs Databases, err error) {
+	syncDir := filepath.Join(dbPath, "sync")
+	if len(repos) == 0 {
+		var files []string
+		if files, err = filepath.Glob(filepath.Join(syncDir, "*.db")); err != nil {
+			return
+		}
+		slices.Sort(files)
+		for _, f := range files {
+			repos = append(repos, strings.TrimSuffix(filepath.Base(f), ".db"))
+		}
+	}
+
+	for _, repo := range repos {
+		var db *Database
+		if db, err = LoadSyncDatabase(filepath.Join(syncDir, repo+".db")); err != nil {
+			return nil, err
+		}
+		dbs = append(dbs, db)
+	}
+
+	return
+}
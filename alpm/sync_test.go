@@ -0,0 +1,136 @@
+package alpm
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func TestLoadSyncDatabases(t *testing.T) {
+	dbs, err := LoadSyncDatabases("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dbs) != 2 || dbs[0].Name != "core" || dbs[1].Name != "extra" {
+		t.Fatalf("unexpected databases: %v", dbs)
+	}
+
+	core := dbs[0]
+	if names := core.Names(); !slices.Equal(names, []string{"bar", "foo"}) {
+		t.Errorf("core.Names() = %v", names)
+	}
+
+	// desc and depends files are merged.
+	foo, ok := core.Get("foo")
+	if !ok {
+		t.Fatal("foo not found in core")
+	}
+	if foo.Version != "1.0-1" || foo.Base != "foo-base" || foo.Repo != "core" {
+		t.Errorf("bad foo: %+v", foo)
+	}
+	if !slices.Equal(foo.Licenses, []string{"MIT", "Apache-2.0"}) {
+		t.Errorf("foo.Licenses = %v", foo.Licenses)
+	}
+	if !slices.Equal(foo.Depends, []string{"glibc", "qt6-base>=6.5"}) {
+		t.Errorf("foo.Depends = %v", foo.Depends)
+	}
+	if !slices.Equal(foo.MakeDepends, []string{"cmake"}) {
+		t.Errorf("foo.MakeDepends = %v", foo.MakeDepends)
+	}
+
+	bar, _ := core.Get("bar")
+	if bar.Version != "2:3.1-2" || !slices.Equal(bar.Conflicts, []string{"bar-git"}) || !slices.Equal(bar.Replaces, []string{"oldbar"}) {
+		t.Errorf("bad bar: %+v", bar)
+	}
+
+	// The uncompressed database is read too.
+	if p, ok := dbs[1].Get("foo"); !ok || p.Repo != "extra" {
+		t.Errorf("foo not found in extra")
+	}
+	if p, ok := dbs.Get("foo"); !ok || p.Repo != "core" {
+		t.Errorf("dbs.Get(foo) should return the package of core")
+	}
+	if !dbs.Contains("libfoo") || dbs.Contains("libbar") {
+		t.Errorf("bad provides")
+	}
+}
+
+func TestLoadSyncDatabasesOrder(t *testing.T) {
+	dbs, err := LoadSyncDatabases("testdata", "extra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dbs) != 1 || dbs[0].Name != "extra" {
+		t.Fatalf("unexpected databases: %v", dbs)
+	}
+	if _, err := LoadSyncDatabases("testdata", "missing"); err == nil {
+		t.Error("missing database should fail")
+	}
+}
+
+func TestReadSyncDatabaseCompression(t *testing.T) {
+	raw, err := os.ReadFile("testdata/sync/extra.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressors := map[string]func(io.Writer) (io.WriteCloser, error){
+		"zstd": func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+		"xz":   func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) },
+	}
+	for name, compressor := range compressors {
+		var buf bytes.Buffer
+		w, err := compressor(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(raw)
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		db, err := ReadSyncDatabase(&buf, "extra")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if _, ok := db.Get("foo"); !ok {
+			t.Errorf("%s: foo not found", name)
+		}
+	}
+
+	if _, err = ReadSyncDatabase(strings.NewReader("not a database"), "bad"); err == nil {
+		t.Error("unknown compression accepted")
+	}
+}
+
+func TestReadRepos(t *testing.T) {
+	conf := `
+[options]
+HoldPkg = pacman glibc
+Architecture = auto
+
+#[testing]
+#Include = /etc/pacman.d/mirrorlist
+
+[core]
+Include = /etc/pacman.d/mirrorlist
+
+[extra]
+Include = /etc/pacman.d/mirrorlist
+
+[build]
+Server = file:///srv/build
+`
+	repos, err := ReadRepos(strings.NewReader(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(repos, []string{"core", "extra", "build"}) {
+		t.Errorf("ReadRepos() = %v", repos)
+	}
+}
@@ -0,0 +1,6 @@
+package alpm
+
+const (
+	errUnknownCompression = "%s: unsupported compression"
+	errInvalidDesc        = "%s: invalid desc file"
+)
@@ -0,0 +1,134 @@
+package alpm
+
+import (
+	"strings"
+)
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+func isAlpha(c byte) bool { return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') }
+func isAlnum(c byte) bool { return isDigit(c) || isAlpha(c) }
+
+// parseEVR splits a full version to epoch, version and release.
+// If the epoch is missing, it is "0". If the release is missing,
+// hasRelease is false.
+func parseEVR(evr string) (epoch, version, release string, hasRelease bool) {
+	i := 0
+	for i < len(evr) && isDigit(evr[i]) {
+		i++
+	}
+	epoch, version = "0", evr
+	if i < len(evr) && evr[i] == ':' {
+		if i > 0 {
+			epoch = evr[:i]
+		}
+		version = evr[i+1:]
+	}
+	if j := strings.LastIndexByte(version, '-'); j >= 0 {
+		version, release, hasRelease = version[:j], version[j+1:], true
+	}
+	return
+}
+
+// rpmvercmp compares two version segments
+// using the algorithm of rpm (and libalpm).
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	one, two := 0, 0
+	ptr1, ptr2 := 0, 0
+	for one < len(a) && two < len(b) {
+		for one < len(a) && !isAlnum(a[one]) {
+			one++
+		}
+		for two < len(b) && !isAlnum(b[two]) {
+			two++
+		}
+		if one >= len(a) || two >= len(b) {
+			break
+		}
+
+		// If the separator lengths are different, we are finished.
+		if s1, s2 := one-ptr1, two-ptr2; s1 != s2 {
+			if s1 < s2 {
+				return -1
+			}
+			return 1
+		}
+
+		ptr1, ptr2 = one, two
+		isNum := isDigit(a[ptr1])
+		isSegment := isAlpha
+		if isNum {
+			isSegment = isDigit
+		}
+		for ptr1 < len(a) && isSegment(a[ptr1]) {
+			ptr1++
+		}
+		for ptr2 < len(b) && isSegment(b[ptr2]) {
+			ptr2++
+		}
+
+		// Segments of different types: numeric is newer.
+		if two == ptr2 {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		s1, s2 := a[one:ptr1], b[two:ptr2]
+		if isNum {
+			s1, s2 = strings.TrimLeft(s1, "0"), strings.TrimLeft(s2, "0")
+			if len(s1) != len(s2) {
+				if len(s1) > len(s2) {
+					return 1
+				}
+				return -1
+			}
+		}
+		if c := strings.Compare(s1, s2); c != 0 {
+			return c
+		}
+
+		one, two = ptr1, ptr2
+	}
+
+	if one >= len(a) && two >= len(b) {
+		return 0
+	}
+
+	// A remaining alpha segment never beats an empty string.
+	if (one >= len(a) && !isAlpha(b[two])) || (one < len(a) && isAlpha(a[one])) {
+		return -1
+	}
+	return 1
+}
+
+// VerCmp compares two full versions (epoch:pkgver-pkgrel)
+// like pacman does. It returns -1 if v1 is older than v2,
+// 0 if they are equal, and 1 if v1 is newer than v2.
+// If one of the versions has no pkgrel, pkgrels are ignored.
+func VerCmp(v1, v2 string) int {
+	if v1 == v2 {
+		return 0
+	}
+	switch {
+	case v1 == "":
+		return -1
+	case v2 == "":
+		return 1
+	}
+
+	e1, ver1, rel1, ok1 := parseEVR(v1)
+	e2, ver2, rel2, ok2 := parseEVR(v2)
+	c := rpmvercmp(e1, e2)
+	if c == 0 {
+		c = rpmvercmp(ver1, ver2)
+		if c == 0 && ok1 && ok2 {
+			c = rpmvercmp(rel1, rel2)
+		}
+	}
+	return c
+}
@@ -0,0 +1,90 @@
+package alpm
+
+import "testing"
+
+// The cases come from the vercmp tests of libalpm.
+var vercmpTests = []struct {
+	v1, v2 string
+	want   int
+}{
+	// Same length, no pkgrel
+	{"1.5.0", "1.5.0", 0},
+	{"1.5.1", "1.5.0", 1},
+
+	// Mixed length
+	{"1.5.1", "1.5", 1},
+
+	// With pkgrel
+	{"1.5.0-1", "1.5.0-1", 0},
+	{"1.5.0-1", "1.5.0-2", -1},
+	{"1.5.0-1", "1.5.1-1", -1},
+	{"1.5.0-2", "1.5.1-1", -1},
+	{"1.5-1", "1.5.1-1", -1},
+	{"1.5-2", "1.5.1-1", -1},
+	{"1.5-2", "1.5.1-2", -1},
+
+	// pkgrel present on one side only: it is ignored
+	{"1.5", "1.5-1", 0},
+	{"1.5-1", "1.5", 0},
+	{"1.1-1", "1.1", 0},
+	{"1.0-1", "1.1", -1},
+	{"1.1-1", "1.0", 1},
+
+	// Alphanumeric versions
+	{"1.5b-1", "1.5-1", -1},
+	{"1.5b", "1.5", -1},
+	{"1.5b-1", "1.5", -1},
+	{"1.5b", "1.5.1", -1},
+	{"1.0a", "1.0", -1},
+	{"1.0a", "1.0alpha", -1},
+	{"1.0alpha", "1.0b", -1},
+	{"1.0b", "1.0beta", -1},
+	{"1.0beta", "1.0rc", -1},
+	{"1.0rc", "1.0", -1},
+
+	// Alpha-dotted versions
+	{"1.0.a", "1.0", 1},
+	{"1.5.a", "1.5", 1},
+	{"1.5.b", "1.5.a", 1},
+	{"1.5.1", "1.5.b", 1},
+	{"1.5.b-1", "1.5.b", 0},
+	{"1.5-1", "1.5.b", -1},
+
+	// Separators: ~ is a separator like the others for libalpm
+	{"2.0", "2_0", 0},
+	{"2.0_a", "2_0.a", 0},
+	{"2.0a", "2.0.a", -1},
+	{"2___a", "2_a", 1},
+	{"1.0~rc1", "1.0.rc1", 0},
+	{"1.0~rc1", "1.0", 1},
+
+	// Epochs
+	{"0:1.0", "0:1.0", 0},
+	{"0:1.0", "0:1.1", -1},
+	{"1:1.0", "0:1.0", 1},
+	{"1:1.0", "0:1.1", 1},
+	{"1:1.0", "2:1.1", -1},
+	{"1:1.0", "0:1.0-1", 1},
+	{"1:1.0-1", "0:1.1-1", 1},
+	{"0:1.0", "1.0", 0},
+	{"0:1.0", "1.1", -1},
+	{"0:1.1", "1.0", 1},
+	{"1:1.0", "1.0", 1},
+	{"1:1.0", "1.1", 1},
+	{"1:1.1", "1.1", 1},
+
+	// Empty versions
+	{"", "", 0},
+	{"", "1.0", -1},
+}
+
+func TestVerCmp(t *testing.T) {
+	for _, tt := range vercmpTests {
+		if got := VerCmp(tt.v1, tt.v2); got != tt.want {
+			t.Errorf("VerCmp(%q, %q) = %d, want %d", tt.v1, tt.v2, got, tt.want)
+		}
+		if got := VerCmp(tt.v2, tt.v1); got != -tt.want {
+			t.Errorf("VerCmp(%q, %q) = %d, want %d", tt.v2, tt.v1, got, -tt.want)
+		}
+	}
+}
@@ -1,23 +1,45 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"codeberg.org/bvaudour/kcp/common"
 	"codeberg.org/bvaudour/kcp/database"
+	"codeberg.org/bvaudour/kcp/pkgbuild"
+	pcheck "codeberg.org/bvaudour/kcp/pkgbuild/check"
 	"git.kaosx.ovh/benjamin/format"
 	"github.com/leonelquinteros/gotext"
 )
 
+// instanceLock is the lock held by the running command, if any.
+var instanceLock *common.Lock
+
 func getIgnore() []string {
 	return strings.Fields(common.Config.Get("kcp.ignore"))
 }
 
+// getHolds returns the holds defined in the configuration
+// and by the --hold flag.
+func getHolds() database.Holds {
+	entries := strings.Fields(common.Config.Get("kcp.holdPkg"))
+	if fHold != nil {
+		entries = append(entries, *fHold...)
+	}
+	return database.ParseHolds(entries...)
+}
+
 func getDbPath() string {
 	return common.JoinIfRelative(common.UserBaseDir, common.Config.Get("kcp.dbFile"))
 }
@@ -26,13 +48,54 @@ func useSsh() bool {
 	return common.Config.Get("kcp.cloneMethod") == "ssh"
 }
 
+// cloneProgress returns the writer of the progress of the clones:
+// the progress is only displayed in a terminal.
+func cloneProgress() io.Writer {
+	if common.IsTerminal(os.Stderr) {
+		return os.Stderr
+	}
+	return nil
+}
+
+// getDb loads the database. If the database file is corrupted,
+// it warns the user and returns the database restored from
+// the backup if any.
+// If the database was written by a newer version of kcp,
+// it exits to not lose its data.
 func getDb() (database.Database, error) {
 	fpath, ignore := getDbPath(), getIgnore()
-	return database.Load(fpath, ignore...)
+	db, err := database.Load(fpath, ignore...)
+	if _, ok := err.(database.VersionError); ok {
+		common.PrintError(err)
+		os.Exit(1)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		common.PrintWarning(err)
+	}
+	if e, ok := err.(database.CorruptedError); ok && e.Recovered {
+		err = nil
+	}
+	return db, err
 }
 
+// updateDb updates the database from the configured sources.
+// The in-flight requests are cancelled if the user interrupts the update.
 func updateDb(db *database.Database, debug bool) (database.Counter, error) {
-	return db.Update(database.NewConnector(), debug)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	counter, err := db.UpdateSources(ctx, database.Sources(db.IgnoreRepos...), debug)
+	if ctx.Err() != nil {
+		common.PrintError(common.Tr(errInterrupt))
+		os.Exit(1)
+	}
+	// All sources were requested, so the cached responses
+	// which were not used are outdated.
+	if err == nil && !common.OfflineFallbackUsed() {
+		if _, e := common.PruneHTTPCache(); e != nil && debug {
+			fmt.Fprintln(os.Stderr, e)
+		}
+	}
+	return counter, err
 }
 
 func loadDb(debug, forceUpdate bool) database.Database {
@@ -45,7 +108,10 @@ func loadDb(debug, forceUpdate bool) database.Database {
 			fmt.Fprintln(os.Stderr, "Trying to update db")
 		}
 		updateDb(&db, debug)
+	} else {
+		db.UpdateLocal()
 	}
+	db.Hold(getHolds())
 	return db
 }
 
@@ -53,20 +119,45 @@ func saveDb(db database.Database) error {
 	return database.Save(getDbPath(), db)
 }
 
-func filter(debug, forceUpdate, onlyName bool, f []database.FilterFunc, s []database.SorterFunc) {
-	db := loadDb(debug, forceUpdate)
-	saveDb(db)
-	l := db.Filter(f...).Sort(s...)
-	if len(l) == 0 {
-		common.PrintWarning(common.Tr(errNoPackage))
-		return
+func printPackages(l database.Packages, onlyName bool, outputFormat string) {
+	var err error
+	switch outputFormat {
+	case database.FormatJSON:
+		err = l.EncodeJSON(os.Stdout)
+	case database.FormatTSV:
+		err = l.EncodeTSV(os.Stdout)
+	default:
+		if len(l) == 0 {
+			common.PrintWarning(common.Tr(errNoPackage))
+			return
+		}
+		if onlyName {
+			names := l.Names()
+			fmt.Println(strings.Join(names, "\n"))
+			return
+		}
+		fmt.Println(l)
 	}
-	if onlyName {
-		names := l.Names()
-		fmt.Println(strings.Join(names, "\n"))
-		return
+	if err != nil {
+		common.PrintError(err)
+		os.Exit(1)
 	}
-	fmt.Println(l)
+}
+
+func filter(debug, forceUpdate, onlyName bool, outputFormat string, f []database.FilterFunc, s []database.SorterFunc) {
+	common.SetOfflineFallback(true)
+	db := loadDb(debug, forceUpdate)
+	// The database is only saved if it was updated under the lock
+	// and not from cached responses: they may be outdated, and once
+	// the date of the last update is saved, the packages changed
+	// in the meantime would be skipped by the next updates.
+	if instanceLock != nil && !common.OfflineFallbackUsed() {
+		if err := saveDb(db); err != nil {
+			common.PrintWarning(err)
+		}
+	}
+	l := db.Filter(f...).Sort(s...)
+	printPackages(l, onlyName, outputFormat)
 }
 
 func getFilters(onlyStarred, onlyInstalled, onlyOutDated bool) []database.FilterFunc {
@@ -82,18 +173,17 @@ func getFilters(onlyStarred, onlyInstalled, onlyOutDated bool) []database.Filter
 	return filters
 }
 
-func getFiltersSearch(search string) []database.FilterFunc {
+func getFiltersSearch(search string, fuzzy bool) []database.FilterFunc {
 	var filters []database.FilterFunc
-	if len(search) > 0 {
-		search = strings.ToLower(search)
-		filters = append(filters, func(p database.Package) bool {
-			name := strings.ToLower(p.Name)
-			if strings.Contains(name, search) {
-				return true
-			}
-			description := strings.ToLower(p.Description)
-			return strings.Contains(description, search)
-		})
+	if len(search) > 0 && fuzzy {
+		filters = append(filters, database.NewFuzzyFilter(search))
+	} else if len(search) > 0 {
+		f, err := database.ParseQuery(search)
+		if err != nil {
+			common.PrintError(err)
+			os.Exit(1)
+		}
+		filters = append(filters, f)
 	}
 	return filters
 }
@@ -119,8 +209,25 @@ func update(debug bool) {
 		fmt.Fprintln(os.Stderr, "Trying to update db")
 	}
 	counters, err := updateDb(&db, debug)
+	if debug {
+		for _, rl := range common.RateLimits() {
+			fmt.Fprintln(os.Stderr, rl)
+		}
+	}
 	if err != nil {
 		common.PrintError(err)
+		var e common.RateLimitError
+		if errors.As(err, &e) {
+			// The rate limits are already displayed in debug mode.
+			if !debug {
+				for _, rl := range common.RateLimits() {
+					common.PrintWarning(rl)
+				}
+			}
+			if common.GetAuthParameters() == nil {
+				common.PrintWarning(common.Tr(msgUseToken))
+			}
+		}
 		os.Exit(1)
 	}
 	if err = saveDb(db); err != nil {
@@ -139,10 +246,11 @@ func list(
 	onlyInstalled,
 	onlyOutDated,
 	sortByStar bool,
+	outputFormat string,
 ) {
 	filters := getFilters(onlyStarred, onlyInstalled, onlyOutDated)
 	sorters := getSorters(sortByStar)
-	filter(debug, forceUpdate, onlyName, filters, sorters)
+	filter(debug, forceUpdate, onlyName, outputFormat, filters, sorters)
 }
 
 func search(
@@ -152,111 +260,571 @@ func search(
 	onlyStarred,
 	onlyInstalled,
 	onlyOutDated,
-	sortByStar bool,
-	substr string,
+	sortByStar,
+	fuzzy bool,
+	substr,
+	outputFormat string,
 ) {
 	filters := getFilters(onlyStarred, onlyInstalled, onlyOutDated)
-	filters = append(filters, getFiltersSearch(substr)...)
+	filters = append(filters, getFiltersSearch(substr, fuzzy)...)
 	sorters := getSorters(sortByStar)
-	filter(debug, forceUpdate, onlyName, filters, sorters)
+	if fuzzy {
+		sorters = append([]database.SorterFunc{database.SortByScore(substr)}, sorters...)
+	}
+	filter(debug, forceUpdate, onlyName, outputFormat, filters, sorters)
 }
 
-func info(debug bool, app string) {
-	db := loadDb(debug, false)
-	p, ok := db.Get(app)
-	if !ok {
-		common.PrintWarning(common.Tr(errNoPackage))
-		os.Exit(1)
+func printSuggestions(db database.Database, app string) {
+	if suggestions := db.Suggest(app, 5); len(suggestions) > 0 {
+		common.PrintWarning(common.Tr(msgDidYouMean, strings.Join(suggestions, ", ")))
 	}
-	fmt.Println(p.Detail())
 }
 
-func get(debug bool, app string) {
+func info(debug bool, app, outputFormat string) {
+	common.SetOfflineFallback(true)
 	db := loadDb(debug, false)
 	p, ok := db.Get(app)
 	if !ok {
-		common.PrintWarning(common.Tr(errNoPackageOrNeedUpdate))
+		common.PrintWarning(common.Tr(errNoPackage))
+		printSuggestions(db, app)
 		os.Exit(1)
 	}
-	wd, _ := os.Getwd()
-	fullDir, err := p.Clone(wd, useSsh())
+	var err error
+	switch outputFormat {
+	case database.FormatJSON:
+		err = p.EncodeJSON(os.Stdout)
+	case database.FormatTSV:
+		err = p.EncodeTSV(os.Stdout)
+	default:
+		fmt.Println(p.Detail())
+	}
 	if err != nil {
 		common.PrintError(err)
 		os.Exit(1)
 	}
-	fmt.Println(common.Tr(msgCloned, app, fullDir))
 }
 
-func install(debug bool, app string, asdep bool) {
+func reverseDepends(debug, onlyName bool, app string) {
+	common.SetOfflineFallback(true)
+	db := loadDb(debug, false)
+	l := db.ReverseDepends(app)
+	if len(l) == 0 {
+		common.PrintWarning(common.Tr(errNoReverseDepends, app))
+		return
+	}
+	var names []string
+	refs := make(map[string][]database.ReverseDepend)
+	for _, r := range l {
+		if _, ok := refs[r.Name]; !ok {
+			names = append(names, r.Name)
+		}
+		refs[r.Name] = append(refs[r.Name], r)
+	}
+	slices.Sort(names)
+	if onlyName {
+		fmt.Println(strings.Join(names, "\n"))
+		return
+	}
+	for _, name := range names {
+		fmt.Print(format.Apply(refs[name][0].Source+"/", "l_majenta"), format.Apply(name, "bold"), "\n")
+		for _, r := range refs[name] {
+			fmt.Printf("\t%s: %s\n", r.Label(), format.Apply(r.Entry, "l_green"))
+		}
+	}
+}
+
+func get(debug bool, app, dest, ref string) {
 	db := loadDb(debug, false)
 	p, ok := db.Get(app)
 	if !ok {
 		common.PrintWarning(common.Tr(errNoPackageOrNeedUpdate))
 		os.Exit(1)
 	}
-	wd := common.Config.Get("kcp.tmpDir")
-	if err := os.MkdirAll(wd, 0755); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fullDir, err := p.Clone(ctx, database.CloneOptions{
+		Dir:      dest,
+		Ref:      ref,
+		Ssh:      useSsh(),
+		Progress: cloneProgress(),
+	})
+	if err != nil {
 		common.PrintError(err)
 		os.Exit(1)
 	}
-	locker := common.JoinIfRelative(wd, common.Config.Get("kcp.lockerFile"))
-	if _, err := os.Open(locker); err == nil {
-		common.PrintError(common.Tr(errOnlyOneInstance))
-		os.Exit(1)
+	fmt.Println(common.Tr(msgCloned, app, fullDir))
+}
+
+func getLockerPath() string {
+	return common.JoinIfRelative(common.Config.Get("kcp.tmpDir"), common.Config.Get("kcp.lockerFile"))
+}
+
+// getLockTimeout returns the configured time (in seconds)
+// to wait for another instance to release the lock.
+func getLockTimeout() int {
+	timeout, _ := strconv.Atoi(common.Config.Get("kcp.lockTimeout"))
+	return timeout
+}
+
+// withLock runs the callback while holding the instance lock.
+// It must wrap every command which writes the database or the temporary dir.
+// wait is the time (in seconds) to wait for another instance
+// to release the lock (0 to fail at once, -1 to wait forever).
+func withLock(debug bool, wait int, cb func()) {
+	locker := getLockerPath()
+	if debug {
+		fmt.Fprintln(os.Stderr, "Trying to lock", locker)
+	}
+	timeout := time.Duration(wait) * time.Second
+	if wait < 0 {
+		timeout = -1
 	}
-	_, err := os.Create(locker)
+	l, err := common.AcquireLock(locker, timeout)
 	if err != nil {
-		common.PrintError(common.Tr(errFailedCreateLocker))
+		if _, ok := err.(common.LockedError); !ok {
+			err = errors.New(common.Tr(errFailedCreateLocker, err.Error()))
+		}
+		common.PrintError(err)
 		os.Exit(1)
 	}
-	installDir, err := p.Clone(wd, useSsh())
-	remove := func() {
-		os.Remove(locker)
-		os.RemoveAll(installDir)
+	if debug && l.StalePid > 0 {
+		fmt.Fprintln(os.Stderr, "Stale lock of PID", l.StalePid, "taken over")
 	}
+	instanceLock = l
+	defer l.Unlock()
+	cb()
+}
+
+// withLockIfUpdate runs the callback of a display command.
+// The lock is only taken if the database must be updated (forced update
+// or missing database), so the display commands don’t need to wait
+// for a running installation.
+func withLockIfUpdate(debug, forceUpdate bool, wait int, cb func()) {
+	if forceUpdate || !common.FileExists(getDbPath()) {
+		withLock(debug, wait, cb)
+		return
+	}
+	cb()
+}
+
+func prepareInstall() (wd string) {
+	wd = common.Config.Get("kcp.tmpDir")
+	if err := os.MkdirAll(wd, 0755); err != nil {
+		common.PrintError(err)
+		os.Exit(1)
+	}
+	return
+}
+
+func onInterrupt(clean func()) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGABRT, syscall.SIGHUP)
 	go func() {
 		<-c
-		remove()
+		clean()
+		instanceLock.Unlock()
 		common.PrintError(common.Tr(errInterrupt))
 		os.Exit(1)
 	}()
-	defer remove()
+}
+
+func getReviewsPath() string {
+	return common.JoinIfRelative(common.UserBaseDir, common.Config.Get("kcp.reviewFile"))
+}
+
+// printPKGBUILDChanges displays a summary of the changes
+// of the PKGBUILD since its approved revision.
+func printPKGBUILDChanges(approved database.Review, dir string) {
+	oldP, err := pkgbuild.Decode(strings.NewReader(approved.Files["PKGBUILD"].Content))
+	if err != nil {
+		return
+	}
+	f, err := os.Open(filepath.Join(dir, "PKGBUILD"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	newP, err := pkgbuild.Decode(f)
+	if err != nil {
+		return
+	}
+	pkgbuild.Diff(oldP, newP).Print(os.Stdout)
+}
+
+// needApproval checks if the approval of the files of a package can be asked.
+// If the user cannot be prompted and no answer is predefined, the unreviewed
+// files are trusted if the setting trustUnreviewed is enabled, else an error
+// explains how to approve them.
+func needApproval(name string, trusted bool) (ask bool, err error) {
+	switch {
+	case common.CanAnswer(qApprove):
+		return true, nil
+	case trusted:
+		return false, nil
+	}
+	return false, errors.New(common.Tr(errCannotApprove, name, qApprove))
+}
+
+// reviewPackage lets the user review the PKGBUILD and the install files
+// of the package, then asks to approve them and records the approval.
+// If the files were already approved, the review is skipped. If they changed
+// since the approved revision, the differences are displayed first,
+// followed by the commits since the last build of the persistent clone.
+// If the user cannot be prompted, the files must be approved by
+// a predefined answer, unless the setting trustUnreviewed is enabled.
+// onEdit is called if the PKGBUILD is edited.
+func reviewPackage(p database.Package, dir string, edit bool, onEdit func()) (err error) {
+	var review database.Review
+	if review, err = database.NewReview(dir); err != nil {
+		return
+	}
+	fpath := getReviewsPath()
+	reviews, err := database.LoadReviews(fpath)
+	if err != nil {
+		common.PrintWarning(err)
+		err = nil
+	}
+	approved, ok := reviews[p.Name]
+	changed := approved.Changes(review)
+
+	switch {
+	case ok && len(changed) == 0:
+		format.FormatOf("l_green").Println(common.Tr(msgReviewed, approved.ApprovedAt.Local().Format(time.DateTime)))
+		return
+	case ok:
+		format.FormatOf("l_yellow").Println(common.Tr(msgReviewChanged, strings.Join(changed, " ")))
+		if slices.Contains(changed, "PKGBUILD") {
+			printPKGBUILDChanges(approved, dir)
+		}
+		if err := approved.Diff(dir, changed...); err != nil {
+			common.PrintWarning(err)
+		}
+	}
+
+	// If the files cannot be approved, the install fails before the review.
+	ask, err := needApproval(p.Name, common.Config.Get("kcp.trustUnreviewed") == common.Yes)
+	if err != nil {
+		return
+	}
+
+	if log, err := database.BuiltLog(context.Background(), dir); err != nil {
+		common.PrintWarning(err)
+	} else if log != "" {
+		format.FormatOf("l_yellow").Println(common.Tr(msgSinceBuilt))
+		fmt.Println(log)
+	}
+
+	if edit {
+		if common.QuestionYN(qEdit, common.Tr(msgEdit), true) {
+			if err = common.EditFile(filepath.Join(dir, "PKGBUILD")); err != nil {
+				return
+			}
+			onEdit()
+		}
+		m, _ := filepath.Glob(filepath.Join(dir, "*.install"))
+		for _, f := range m {
+			i := filepath.Base(f)
+			if slices.Contains(changed, i) && common.QuestionYN(qEditInstall, common.Tr(msgEditInstall, i), false) {
+				if err = common.EditFile(f); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	if !ask {
+		common.PrintWarning(common.Tr(msgNotReviewed, strings.Join(changed, " ")))
+		return
+	}
+	if !common.QuestionYN(qApprove, common.Tr(msgApprove), false) {
+		return errors.New(common.Tr(errNotApproved, p.Name))
+	}
+
+	// The files may have been edited: the approval is for their final content.
+	if review, err = database.NewReview(dir); err != nil {
+		return
+	}
+	review.ApprovedAt = time.Now()
+	reviews[p.Name] = review
+	if err := database.SaveReviews(fpath, reviews); err != nil {
+		common.PrintWarning(err)
+	}
+	return
+}
+
+// checkPKGBUILD runs the checks of pckcp on the PKGBUILD of the dir
+// and displays the warnings and the errors.
+// It returns an error if the errors should block the install.
+func checkPKGBUILD(db database.Database, dir string) error {
+	mode := common.Config.Get("kcp.checkPkgbuild")
+	if mode == checkNo {
+		return nil
+	}
+	_, report, err := pcheck.RunFile(dir, pcheck.Options{
+		InKCP: func(name string) bool {
+			_, ok := db.Provider(name)
+			return ok
+		},
+	})
+	if err != nil {
+		common.PrintWarning(err)
+	}
+	format.FormatOf("l_yellow").Println(common.Tr(msgChecks, report.Summary()))
+	report.Print(os.Stdout, pcheck.Warning)
+	if mode == checkBlock && (err != nil || report.HasErrors()) {
+		return errors.New(common.Tr(errChecksFailed))
+	}
+	return nil
+}
+
+// buildPackage clones the package in the working dir
+// (or updates its persistent clone if the clone cache is enabled),
+// checks out its pin if any, checks the PKGBUILD, lets the user
+// review the PKGBUILD and the install files, then builds and installs the package.
+// The files cannot be edited if the standard input is not a terminal.
+func buildPackage(db database.Database, p database.Package, wd string, asdep, noEdit bool) (err error) {
+	var installDir string
+	cacheDir := common.CloneCacheDir()
+	if cacheDir == "" {
+		if installDir, err = p.Clone(context.Background(), database.CloneOptions{
+			Dir:      filepath.Join(wd, p.Name),
+			Ssh:      useSsh(),
+			Progress: cloneProgress(),
+		}); err != nil {
+			return
+		}
+		defer os.RemoveAll(installDir)
+	} else if installDir, err = p.Sync(context.Background(), cacheDir, useSsh(), cloneProgress()); err != nil {
+		if !errors.As(err, new(database.FetchError)) {
+			return
+		}
+		common.PrintWarning(err)
+		err = nil
+	}
+	if p.Hold != nil && p.Hold.IsPin() {
+		format.FormatOf("l_yellow").Println(common.Tr(msgPinned, p.Hold.String()))
+		if err = p.Hold.Checkout(context.Background(), installDir); err != nil {
+			return
+		}
+	}
+	checkErr := checkPKGBUILD(db, installDir)
+	edit := !noEdit && common.IsTerminal(os.Stdin)
+	if err = reviewPackage(p, installDir, edit, func() {
+		checkErr = checkPKGBUILD(db, installDir)
+	}); err != nil {
+		return
+	}
+	if checkErr != nil {
+		return checkErr
+	}
+	args := []string{"-si"}
+	if asdep {
+		args = append(args, "--asdeps")
+	}
+	if cacheDir != "" {
+		// The package can already be built in the persistent clone.
+		args = append(args, "--force")
+	}
+	if !common.Interactive() {
+		args = append(args, "--noconfirm")
+	}
+	defer common.ResetLocalDatabase()
+	if err = common.LaunchCommandIn(installDir, "makepkg", args...); err == nil && cacheDir != "" {
+		if err := database.MarkBuilt(context.Background(), installDir); err != nil {
+			common.PrintWarning(err)
+		}
+	}
+	return
+}
+
+func install(debug bool, app string, asdep, noEdit bool) {
+	db := loadDb(debug, false)
+	if !db.Contains(app) {
+		common.PrintWarning(common.Tr(errNoPackageOrNeedUpdate))
+		printSuggestions(db, app)
+		os.Exit(1)
+	}
+	l, err := db.ResolveDepends(app)
 	if err != nil {
-		remove()
 		common.PrintError(err)
 		os.Exit(1)
 	}
-	os.Chdir(installDir)
-	if common.QuestionYN(common.Tr(msgEdit), true) {
-		if err = common.EditFile("PKGBUILD"); err != nil {
+	if deps := l[:len(l)-1]; len(deps) > 0 {
+		format.FormatOf("l_yellow").Println(common.Tr(msgKcpDepends, app))
+		fmt.Println(strings.Join(deps.Names(), " "))
+		if !common.QuestionYN(qDepends, common.Tr(msgInstallDepends), true) {
+			os.Exit(1)
+		}
+	}
+	wd := prepareInstall()
+	remove := func() {
+		for _, p := range l {
+			os.RemoveAll(filepath.Join(wd, p.Name))
+		}
+	}
+	onInterrupt(remove)
+	for i, p := range l {
+		isDep := i < len(l)-1
+		if isDep {
+			format.FormatOf("l_yellow").Println(common.Tr(msgInstallingDepend, p.Name))
+		}
+		if err := buildPackage(db, p, wd, asdep || isDep, noEdit); err != nil {
 			remove()
 			common.PrintError(err)
 			os.Exit(1)
 		}
+		p.LocalVersion = p.GetLocaleVersion()
+		db.Set(p)
+		if err := saveDb(db); err != nil {
+			common.PrintWarning(err)
+		}
 	}
-	m, _ := filepath.Glob("*.install")
-	for _, i := range m {
-		if common.QuestionYN(common.Tr(msgEditInstall, i), false) {
-			if err := common.EditFile(i); err != nil {
-				remove()
-				common.PrintError(err)
-				os.Exit(1)
-			}
+	remove()
+}
+
+func cleanCache(debug bool) {
+	n, err := common.CleanHTTPCache()
+	if err != nil {
+		common.PrintError(err)
+		os.Exit(1)
+	}
+	format.FormatOf("l_green").Println(common.Tr(msgHTTPCacheCleaned, n))
+
+	cacheDir := common.CloneCacheDir()
+	if cacheDir == "" {
+		return
+	}
+	db := loadDb(debug, false)
+	removed, err := db.CleanClones(cacheDir)
+	for _, name := range removed {
+		fmt.Println(common.Tr(msgCloneRemoved, name))
+	}
+	if err != nil {
+		common.PrintError(err)
+		os.Exit(1)
+	}
+	format.FormatOf("l_green").Println(common.Tr(msgCacheCleaned, len(removed)))
+}
+
+func printUpgradePlan(l database.Packages) {
+	s := 0
+	for _, p := range l {
+		s = max(s, utf8.RuneCountInString(p.Name))
+	}
+	w := len(strconv.Itoa(len(l)))
+	for i, p := range l {
+		fmt.Printf(
+			"%*d %s%s %s -> %s\n",
+			w,
+			i+1,
+			format.Apply(p.Name, "bold"),
+			strings.Repeat(" ", s-utf8.RuneCountInString(p.Name)),
+			format.Apply(p.LocalVersion, "l_red"),
+			format.Apply(p.RepoVersion, "l_green"),
+		)
+	}
+}
+
+// getSkipped parses the response of the user and returns
+// the names of the packages to skip. The response can
+// contain indexes (starting at 1), ranges of indexes
+// (like 2-4) or names of packages.
+func getSkipped(l database.Packages, response string) (skipped []string) {
+	for entry := range strings.FieldsSeq(strings.ReplaceAll(response, ",", " ")) {
+		if l.Contains(entry) {
+			skipped = append(skipped, entry)
+			continue
+		}
+		begin, end, isRange := strings.Cut(entry, "-")
+		if !isRange {
+			end = begin
+		}
+		b, err1 := strconv.Atoi(begin)
+		e, err2 := strconv.Atoi(end)
+		if err1 != nil || err2 != nil {
+			common.PrintWarning(common.Tr(errInvalidSelection, entry))
+			continue
+		}
+		for i := max(b, 1); i <= min(e, len(l)); i++ {
+			skipped = append(skipped, l[i-1].Name)
 		}
 	}
-	args := []string{"-si"}
-	if asdep {
-		args = append(args, "--asdeps")
+	return
+}
+
+func printUpgradeSummary(upgraded, skipped []string, failed map[string]error) {
+	fmt.Println()
+	format.FormatOf("yellow").Println(common.Tr(msgUpgradeSummary))
+	if len(upgraded) > 0 {
+		fmt.Println(common.Tr(msgUpgraded, len(upgraded)), strings.Join(upgraded, " "))
+	}
+	if len(skipped) > 0 {
+		fmt.Println(common.Tr(msgSkipped, len(skipped)), strings.Join(skipped, " "))
+	}
+	if len(failed) > 0 {
+		common.PrintError(common.Tr(msgFailed, len(failed)))
+		names := make([]string, 0, len(failed))
+		for name := range failed {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			common.PrintError(fmt.Sprintf("  - %s: %s", name, failed[name]))
+		}
+	}
+}
+
+func upgrade(debug, forceUpdate, noEdit bool) {
+	db := loadDb(debug, forceUpdate)
+	outdated := db.Filter(database.FilterOutdated).Sort(database.SortByName)
+	if held := outdated.Filter(database.FilterHeld); len(held) > 0 {
+		format.FormatOf("yellow").Println(common.Tr(msgHeld, len(held)), strings.Join(held.Names(), " "))
+	}
+	l := outdated.Filter(database.FilterNotHeld)
+	if len(l) == 0 {
+		format.FormatOf("yellow").Println(common.Tr(msgNothingToUpgrade))
+		return
+	}
+
+	printUpgradePlan(l)
+	fmt.Println()
+	skipped := getSkipped(l, common.Question(qSkip, common.Tr(msgSkip)))
+	l.Remove(l.Filter(func(p database.Package) bool { return slices.Contains(skipped, p.Name) })...)
+	if len(l) == 0 || !common.QuestionYN(qUpgrade, common.Tr(msgConfirmUpgrade, len(l)), true) {
+		return
+	}
+
+	wd := prepareInstall()
+	remove := func() {
+		for _, p := range l {
+			os.RemoveAll(filepath.Join(wd, p.Name))
+		}
 	}
-	if err := common.LaunchCommand("makepkg", args...); err != nil {
+	onInterrupt(remove)
+	defer remove()
+
+	var upgraded []string
+	failed := make(map[string]error)
+	for _, p := range l {
+		format.FormatOf("l_yellow").Println(common.Tr(msgUpgrading, p.Name))
+		if err := buildPackage(db, p, wd, false, noEdit); err != nil {
+			common.PrintError(err)
+			failed[p.Name] = err
+			continue
+		}
+		p.LocalVersion = p.GetLocaleVersion()
+		db.Set(p)
+		if err := saveDb(db); err != nil {
+			common.PrintWarning(err)
+		}
+		upgraded = append(upgraded, p.Name)
+	}
+
+	printUpgradeSummary(upgraded, skipped, failed)
+	if len(failed) > 0 {
 		remove()
-		common.PrintError(err)
 		os.Exit(1)
 	}
-	p.LocalVersion = p.GetLocaleVersion()
-	saveDb(db)
 }
 
 func debugLocales() {
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"codeberg.org/bvaudour/kcp/common"
+)
+
+func TestNeedApproval(t *testing.T) {
+	common.SetNoConfirm(true)
+	defer common.SetNoConfirm(false)
+	for _, env := range []string{common.EnvAnswersFile, common.EnvAnswers} {
+		t.Setenv(env, "")
+		os.Unsetenv(env)
+	}
+
+	// Without prompt nor answer, the unreviewed files are only trusted on demand.
+	if ask, err := needApproval("foo", true); ask || err != nil {
+		t.Errorf("trusted: %v, %v", ask, err)
+	}
+	ask, err := needApproval("foo", false)
+	if ask || err == nil {
+		t.Fatalf("untrusted: %v, %v", ask, err)
+	}
+	for _, s := range []string{"foo", "KCP_ANSWERS='approve=y'", "trustUnreviewed"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q doesn't mention %s", err, s)
+		}
+	}
+}
@@ -6,26 +6,40 @@ const (
 
 With this tool, you can search, get and install a package from KaOS Community Packages.`
 	appDescription = "Tool in command-line for KaOS Community Packages"
-	synopsis       = "(-h|-v|-u|(-l|-s <app>) [-fxNSIO]|-i <app> [-d]|-g <app>|-V <app>)"
+	synopsis       = "(-h|-v|-u|-U [-f]|(-l|-s <app> [-z]) [-fxNSIO] [-F <format>]|-i <app> [-d]|-g <app> [--dest <dir>] [--ref <ref>]|-V <app> [-F <format>]|-R <app> [-N]|--clean-cache) [-H <app>…] [--noconfirm] [--noedit] [-w <seconds>]"
 	dHelp          = "Print this help"
 	dVersion       = "Print version"
 	dList          = "Display all packages of KCP"
 	dUpdate        = "Refresh the local database"
 	dSearch        = "Search packages in KCP and display them"
 	dGet           = "Download needed files to build a package"
+	dDest          = "On get action, directory of the clone (default: ./<app>)"
+	dRef           = "On get action, branch, tag or commit to check out"
 	dInstall       = "Install a package from KCP"
+	dUpgrade       = "Upgrade all outdated packages from KCP"
 	dFast          = "On display action, don't print KCP version"
 	dSort          = "On display action, sort packages by stars descending"
 	dAsDeps        = "On install action, install as a dependence"
 	dInstalled     = "On list action, display only installed packages"
 	dComplete      = "On refreshing database action, force complete update"
-	dForceUpdate   = "On display action, force refreshing local database"
+	dForceUpdate   = "On display or upgrade action, force refreshing local database"
 	dOnlyName      = "On display action, display only the name of the package"
 	dOnlystarred   = "On display action, display only packages with at least one star"
 	dOnlyInstalled = "On display action, display only installed packages"
 	dOnlyOutdated  = "On display action, display only outdated packages"
 	dInformation   = "Display informations about a package"
+	dReverse       = "Display the packages of KCP which depend on a package"
+	dOutputFormat  = "On display action, output format of the packages"
+	dFuzzy         = "On search action, use fuzzy matching and sort by relevance"
+	dNoConfirm     = "On install or upgrade action, do not ask for any confirmation (implies --noedit)"
+	dNoEdit        = "On install or upgrade action, do not propose to edit the PKGBUILD and the install files"
+	dHold          = "Hold back the upgrades of the given packages (globs allowed, name@commit or name=version to pin)"
+	dCleanCache    = "Remove the cached HTTP responses and the cached clones of the packages which are not installed anymore"
+	dWait          = "Time to wait for another instance to finish (0 to fail at once, -1 to wait forever)"
 	dValueName     = "<app>"
+	dSecondsName   = "<seconds>"
+	dDirName       = "<dir>"
+	dRefName       = "<ref>"
 )
 
 // Messages
@@ -33,11 +47,61 @@ const (
 	errNoRoot                = "Don't launch this program as root!"
 	errNoPackage             = "No package found"
 	errNoPackageOrNeedUpdate = "No package found. Check if the database is updated."
-	errOnlyOneInstance       = "Another instance of kcp is running!"
-	errFailedCreateLocker    = "Failed to create locker file!"
+	errNoReverseDepends      = "No package refers to %s"
+	errFailedCreateLocker    = "Failed to create locker file: %v"
 	errInterrupt             = "Interrupt by user…"
+	errInvalidSelection      = "Invalid selection: %s"
+	errChecksFailed          = "The PKGBUILD has errors: install cancelled (see setting checkPkgbuild)"
+	errNotApproved           = "The files of %s were not approved: install cancelled (see setting trustUnreviewed)"
+	errCannotApprove         = "The files of %s must be approved, but the user cannot be prompted: approve them with KCP_ANSWERS='%s=y' or enable the setting trustUnreviewed"
 
+	msgDidYouMean  = "Did you mean: %s?"
+	msgUseToken    = "Set a token in the [git] section of the configuration to increase the limit."
 	msgCloned      = "Package %s cloned in %s."
 	msgEdit        = "Do you want to edit PKGBUILD?"
 	msgEditInstall = "Do you want to edit %s?"
+	msgPinned      = "Checking out pin %s…"
+	msgChecks      = "PKGBUILD checks: %s"
+
+	msgReviewed      = "PKGBUILD and install files unchanged since their approval (%s)."
+	msgReviewChanged = "Files changed since their approval: %s"
+	msgNotReviewed   = "Files not reviewed: %s"
+	msgApprove       = "Approve these files and build the package?"
+	msgSinceBuilt    = "Commits since the last build:"
+
+	msgCloneRemoved     = "Clone of %s removed."
+	msgCacheCleaned     = "%d clone(s) removed."
+	msgHTTPCacheCleaned = "%d cached HTTP response(s) removed."
+
+	msgKcpDepends       = "%s needs the following packages from KCP:"
+	msgInstallDepends   = "Install them as dependencies first?"
+	msgInstallingDepend = "Installing dependency %s…"
+
+	msgNothingToUpgrade = "All packages are up to date."
+	msgSkip             = "Packages to skip (eg: 1 2 4-6, leave blank to upgrade all):"
+	msgConfirmUpgrade   = "Upgrade %d package(s)?"
+	msgUpgrading        = "Upgrading %s…"
+	msgUpgradeSummary   = "Upgrade summary:"
+	msgUpgraded         = "%d package(s) upgraded:"
+	msgSkipped          = "%d package(s) skipped:"
+	msgHeld             = "%d package(s) held back:"
+	msgFailed           = "%d package(s) failed:"
+)
+
+// Ids of the questions, to give them predefined answers
+// (eg. KCP_ANSWERS='approve=y').
+const (
+	qEdit        = "edit"
+	qEditInstall = "edit-install"
+	qApprove     = "approve"
+	qDepends     = "depends"
+	qSkip        = "skip"
+	qUpgrade     = "upgrade"
+)
+
+// Values of the setting checkPkgbuild
+const (
+	checkNo    = "no"
+	checkWarn  = "warn"
+	checkBlock = "block"
 )
@@ -4,15 +4,20 @@ import (
 	"os"
 
 	"codeberg.org/bvaudour/kcp/common"
+	"codeberg.org/bvaudour/kcp/database"
 	"codeberg.org/bvaudour/kcp/flag"
 )
 
 var (
 	flags                                                        *flag.Parser
-	fHelp, fVersion, fList, fUpdate                              *bool
-	fSearch, fGet, fInstall, fInfo                               *string
+	fHelp, fVersion, fList, fUpdate, fUpgrade                    *bool
+	fSearch, fGet, fInstall, fInfo, fReverse, fOutputFormat      *string
+	fDest, fRef                                                  *string
 	fSorted, fOnlyName, fOnlyStar, fOnlyInstalled, fOnlyOutdated *bool
-	fForceUpdate, fAsDepend, fDebug                              *bool
+	fForceUpdate, fAsDepend, fFuzzy, fDebug                      *bool
+	fNoConfirm, fNoEdit, fCleanCache                             *bool
+	fWait                                                        *int
+	fHold                                                        *[]string
 )
 
 func initFlags() {
@@ -26,25 +31,44 @@ func initFlags() {
 	fUpdate, _ = flags.Bool("-u", "--update-database", common.Tr(dUpdate))
 	fSearch, _ = flags.String("-s", "--search", common.Tr(dSearch), common.Tr(dValueName), "")
 	fGet, _ = flags.String("-g", "--get", common.Tr(dGet), common.Tr(dValueName), "")
+	fDest, _ = flags.String("", "--dest", common.Tr(dDest), common.Tr(dDirName), "")
+	fRef, _ = flags.String("", "--ref", common.Tr(dRef), common.Tr(dRefName), "")
 	fInstall, _ = flags.String("-i", "--install", common.Tr(dInstall), common.Tr(dValueName), "")
+	fUpgrade, _ = flags.Bool("-U", "--upgrade", common.Tr(dUpgrade))
 	fSorted, _ = flags.Bool("-x", "--sort", common.Tr(dSort))
 	fForceUpdate, _ = flags.Bool("-f", "--force-update", common.Tr(dForceUpdate))
+	fFuzzy, _ = flags.Bool("-z", "--fuzzy", common.Tr(dFuzzy))
 	fOnlyName, _ = flags.Bool("-N", "--only-name", common.Tr(dOnlyName))
 	fOnlyStar, _ = flags.Bool("-S", "--only-starred", common.Tr(dOnlystarred))
 	fOnlyInstalled, _ = flags.Bool("-I", "--only-installed", common.Tr(dOnlyInstalled))
 	fOnlyOutdated, _ = flags.Bool("-O", "--only-outdated", common.Tr(dOnlyOutdated))
 	fAsDepend, _ = flags.Bool("-d", "--asdeps", common.Tr(dAsDeps))
 	fInfo, _ = flags.String("-V", "--information", common.Tr(dInformation), common.Tr(dValueName), "")
+	fOutputFormat, _ = flags.Choice("-F", "--format", common.Tr(dOutputFormat), "", database.OutputFormats())
+	fReverse, _ = flags.String("-R", "--reverse-depends", common.Tr(dReverse), common.Tr(dValueName), "")
+	fNoConfirm, _ = flags.Bool("", "--noconfirm", common.Tr(dNoConfirm))
+	fNoEdit, _ = flags.Bool("", "--noedit", common.Tr(dNoEdit))
+	fHold, _ = flags.Array("-H", "--hold", common.Tr(dHold), common.Tr(dValueName))
+	fCleanCache, _ = flags.Bool("", "--clean-cache", common.Tr(dCleanCache))
+	fWait, _ = flags.Int("-w", "--wait", common.Tr(dWait), common.Tr(dSecondsName), getLockTimeout())
 	fDebug, _ = flags.Bool("", "--debug", "")
 
-	flags.Group("-h", "-v", "-l", "-s", "-g", "-i", "-u", "--information")
+	flags.Group("-h", "-v", "-l", "-s", "-g", "-i", "-u", "-U", "--information", "--reverse-depends", "--clean-cache")
 	flags.Require("--sort", "-l", "-s")
-	flags.Require("--force-update", "-l", "-s")
-	flags.Require("--only-name", "-l", "-s")
+	flags.Require("--force-update", "-l", "-s", "-U")
+	flags.Require("--only-name", "-l", "-s", "-R")
 	flags.Require("--only-starred", "-l", "-s")
 	flags.Require("--only-installed", "-l", "-s")
 	flags.Require("--only-outdated", "-l", "-s")
+	flags.Require("--fuzzy", "-s")
+	flags.Require("--format", "-l", "-s", "-V")
 	flags.Require("--asdeps", "-i")
+	flags.Require("--dest", "-g")
+	flags.Require("--ref", "-g")
+	flags.Require("--noconfirm", "-i", "-U")
+	flags.Require("--noedit", "-i", "-U")
+	flags.Require("--hold", "-l", "-s", "-i", "-U", "--information")
+	flags.Require("--wait", "-l", "-s", "-u", "-i", "-U", "--clean-cache")
 	flags.GetFlag("--debug").Set(flag.Hidden, true)
 }
 
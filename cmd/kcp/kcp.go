@@ -1,9 +1,15 @@
 package main
 
+import (
+	"codeberg.org/bvaudour/kcp/common"
+)
+
 func main() {
 	checkUser()
 	initFlags()
 	parseFlags()
+	common.SetNoConfirm(*fNoConfirm)
+	noEdit := *fNoEdit || *fNoConfirm
 	switch {
 	case *fHelp:
 		if *fDebug {
@@ -13,16 +19,34 @@ func main() {
 	case *fVersion:
 		flags.PrintVersion()
 	case *fUpdate:
-		update(*fDebug)
+		withLock(*fDebug, *fWait, func() {
+			update(*fDebug)
+		})
 	case *fList:
-		list(*fDebug, *fForceUpdate, *fOnlyName, *fOnlyStar, *fOnlyInstalled, *fOnlyOutdated, *fSorted)
+		withLockIfUpdate(*fDebug, *fForceUpdate, *fWait, func() {
+			list(*fDebug, *fForceUpdate, *fOnlyName, *fOnlyStar, *fOnlyInstalled, *fOnlyOutdated, *fSorted, *fOutputFormat)
+		})
 	case *fSearch != "":
-		search(*fDebug, *fForceUpdate, *fOnlyName, *fOnlyStar, *fOnlyInstalled, *fOnlyOutdated, *fSorted, *fSearch)
+		withLockIfUpdate(*fDebug, *fForceUpdate, *fWait, func() {
+			search(*fDebug, *fForceUpdate, *fOnlyName, *fOnlyStar, *fOnlyInstalled, *fOnlyOutdated, *fSorted, *fFuzzy, *fSearch, *fOutputFormat)
+		})
 	case *fInfo != "":
-		info(*fDebug, *fInfo)
+		info(*fDebug, *fInfo, *fOutputFormat)
+	case *fReverse != "":
+		reverseDepends(*fDebug, *fOnlyName, *fReverse)
 	case *fGet != "":
-		get(*fDebug, *fGet)
+		get(*fDebug, *fGet, *fDest, *fRef)
 	case *fInstall != "":
-		install(*fDebug, *fInstall, *fAsDepend)
+		withLock(*fDebug, *fWait, func() {
+			install(*fDebug, *fInstall, *fAsDepend, noEdit)
+		})
+	case *fUpgrade:
+		withLock(*fDebug, *fWait, func() {
+			upgrade(*fDebug, *fForceUpdate, noEdit)
+		})
+	case *fCleanCache:
+		withLock(*fDebug, *fWait, func() {
+			cleanCache(*fDebug)
+		})
 	}
 }
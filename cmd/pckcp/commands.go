@@ -7,7 +7,10 @@ import (
 
 	"codeberg.org/bvaudour/kcp/common"
 	"codeberg.org/bvaudour/kcp/pkgbuild"
+	"codeberg.org/bvaudour/kcp/pkgbuild/audit"
+	pcheck "codeberg.org/bvaudour/kcp/pkgbuild/check"
 	pformat "codeberg.org/bvaudour/kcp/pkgbuild/format"
+	fformat "git.kaosx.ovh/benjamin/format"
 )
 
 func generate(clean, debug bool, output string) {
@@ -73,22 +76,25 @@ func check(edit, debug bool, output string) {
 	}
 
 	// Checkers
-	var options []pformat.FormatOption
-	options = append(options, checkHeader(p, edit)...)
-	options = append(options, checkDuplicates(p, edit)...)
-	newNodes := checkMissingVars(p, edit)
-	checkMissingFuncs(p, edit)
-	options = append(options, checkInfoTypes(p, edit)...)
-	rempve := checkEmpty(p, edit)
-	checkPkgrel(p, edit)
-	checkArch(p, edit)
-	checkDepends(p, edit)
-	checkInstall(p, edit)
+	// The questions of the fixes depend on the PKGBUILD,
+	// so they have no id for the predefined answers.
+	report := pcheck.Run(p, pcheck.Options{})
+	for _, m := range report.Messages {
+		m.Print(os.Stdout)
+		if !edit || m.Fix == nil || !common.QuestionYN("", m.Fix.Question, m.Fix.Default) {
+			continue
+		}
+		var value string
+		if m.Fix.Prompt != "" {
+			value = common.Question("", m.Fix.Prompt)
+		}
+		m.Fix.Apply(value)
+	}
 
 	if edit {
-		p.Add(newNodes...)
-		p.Remove(rempve...)
-		if common.QuestionYN(common.Tr(questionFormat), true) {
+		options := report.Changes.Options
+		report.Changes.Apply(p)
+		if common.QuestionYN("format", common.Tr(questionFormat), true) {
 			options = append(options,
 				pformat.OptionRemoveOuterComments,
 				pformat.OptionRemoveInnerComments,
@@ -185,3 +191,68 @@ func format(debug bool, output string) {
 		common.PrintWarning(common.Tr(warnSaved, output))
 	}
 }
+
+func auditPKGBUILD() {
+	f, err := os.Open("PKGBUILD")
+	if err != nil {
+		common.PrintError(err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	findings, err := audit.Parse(f)
+	if err != nil {
+		common.PrintError(err)
+		os.Exit(1)
+	}
+	if len(findings) == 0 {
+		fformat.FormatOf("l_green").Println(common.Tr(infoNoRisk))
+		return
+	}
+	for _, finding := range findings {
+		color := "l_yellow"
+		if finding.Severity == audit.Dangerous {
+			color = "l_red"
+		}
+		fmt.Printf(
+			"%s:\n  %s\n  %s: %s\n",
+			fformat.FormatOf(color).Sprintf("%s (L.%d)", finding.Severity, finding.Line),
+			finding.Text,
+			finding.Scope(),
+			finding.Code,
+		)
+	}
+	if findings.Count(audit.Dangerous) > 0 {
+		os.Exit(1)
+	}
+}
+
+func decodeFile(fpath string) (*pkgbuild.PKGBUILD, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return pkgbuild.Decode(f)
+}
+
+func diff(old string) {
+	oldP, err := decodeFile(old)
+	if err != nil {
+		common.PrintError(err)
+		os.Exit(1)
+	}
+	newP, err := decodeFile("PKGBUILD")
+	if err != nil {
+		common.PrintError(err)
+		os.Exit(1)
+	}
+
+	changes := pkgbuild.Diff(oldP, newP)
+	if len(changes) == 0 {
+		fformat.FormatOf("l_green").Println(common.Tr(infoNoChange))
+		return
+	}
+	changes.Print(os.Stdout)
+}
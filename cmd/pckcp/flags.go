@@ -10,7 +10,8 @@ import (
 var (
 	flags                                                      *flag.Parser
 	fHelp, fVersion, fEdit, fDebug, fGenerate, fClean, fFormat *bool
-	fOutput                                                    *string
+	fAudit                                                     *bool
+	fOutput, fDiff                                             *string
 )
 
 func initFlags() {
@@ -23,6 +24,8 @@ func initFlags() {
 	fEdit, _ = flags.Bool("-e", "--edit", common.Tr(interactiveEdit))
 	fGenerate, _ = flags.Bool("-g", "--generate", common.Tr(generatePrototype))
 	fFormat, _ = flags.Bool("-f", "--format", common.Tr(formatFile))
+	fAudit, _ = flags.Bool("-a", "--audit", common.Tr(auditFile))
+	fDiff, _ = flags.String("-D", "--diff", common.Tr(diffFile), common.Tr(dFileName), "")
 	fOutput, _ = flags.String("-o", "--output", common.Tr(formatedOutput), common.Tr(dFileName), "")
 
 	fClean, _ = flags.Bool("-c", "--clean", common.Tr(cleanUseless))
@@ -30,7 +33,7 @@ func initFlags() {
 
 	fDebug, _ = flags.Bool("-d", "--debug", "")
 	flags.GetFlag("--debug").Set(flag.Hidden, true)
-	flags.Group("-h", "-e", "-v", "-g", "-f")
+	flags.Group("-h", "-e", "-v", "-g", "-f", "-a", "-D")
 }
 
 func parseFlags() {
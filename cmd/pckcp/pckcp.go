@@ -13,6 +13,10 @@ func main() {
 		generate(*fClean, *fDebug, *fOutput)
 	case *fFormat:
 		format(*fDebug, *fOutput)
+	case *fAudit:
+		auditPKGBUILD()
+	case *fDiff != "":
+		diff(*fDiff)
 	default:
 		check(*fEdit, *fDebug, *fOutput)
 	}
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/term"
+)
+
+// Environment variables giving predefined answers to the questions.
+// An answer can be given for a question by prefixing it with the id
+// of the question (eg. approve=y): it is used each time the question
+// is asked. The other answers are used in order by the questions
+// without such an answer.
+const (
+	// EnvAnswers contains the answers, separated by semicolons.
+	EnvAnswers = "KCP_ANSWERS"
+
+	// EnvAnswersFile is the path of a file containing one answer per line.
+	EnvAnswersFile = "KCP_ANSWERS_FILE"
+)
+
+var noConfirm atomic.Bool
+
+var answers = struct {
+	sync.Mutex
+	once sync.Once
+	list []string
+	byID map[string]string
+}{}
+
+// SetNoConfirm enables or disables the non-interactive mode:
+// if enabled, the questions without predefined answer
+// get their default response, without prompting the user.
+func SetNoConfirm(enabled bool) {
+	noConfirm.Store(enabled)
+}
+
+// IsTerminal checks if the file is a terminal.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Interactive returns true if the user can be prompted,
+// ie. the non-interactive mode is disabled and the standard
+// input is a terminal.
+func Interactive() bool {
+	return !noConfirm.Load() && IsTerminal(os.Stdin)
+}
+
+// isAnswerID checks if the string can be the id of a question
+// (lowercase letters and dashes).
+func isAnswerID(s string) bool {
+	return s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyz-") == ""
+}
+
+// addAnswer adds a predefined answer, given with
+// or without the id of its question.
+func addAnswer(answer string) {
+	answer = strings.TrimSpace(answer)
+	if id, value, ok := strings.Cut(answer, "="); ok {
+		if id = strings.TrimSpace(id); isAnswerID(id) {
+			answers.byID[id] = strings.TrimSpace(value)
+			return
+		}
+	}
+	answers.list = append(answers.list, answer)
+}
+
+// loadAnswers reads the predefined answers from the environment.
+// The answers of the file are given before the answers of the variable.
+func loadAnswers() {
+	answers.byID = make(map[string]string)
+	if fpath := os.Getenv(EnvAnswersFile); fpath != "" {
+		if f, err := os.Open(fpath); err != nil {
+			PrintWarning(err)
+		} else {
+			sc := bufio.NewScanner(f)
+			for sc.Scan() {
+				addAnswer(sc.Text())
+			}
+			f.Close()
+		}
+	}
+	if env, ok := os.LookupEnv(EnvAnswers); ok {
+		for a := range strings.SplitSeq(env, ";") {
+			addAnswer(a)
+		}
+	}
+}
+
+// nextAnswer returns the predefined answer of the question with the given id
+// or, if there is none, pops the next answer given without id.
+func nextAnswer(id string) (answer string, ok bool) {
+	answers.Lock()
+	defer answers.Unlock()
+	answers.once.Do(loadAnswers)
+	if answer, ok = answers.byID[id]; ok {
+		return
+	}
+	if len(answers.list) == 0 {
+		return
+	}
+	answer, answers.list = answers.list[0], answers.list[1:]
+	return answer, true
+}
+
+// CanAnswer checks if the question with the given id can be answered:
+// the user can be prompted or a predefined answer is available.
+func CanAnswer(id string) bool {
+	if Interactive() {
+		return true
+	}
+	answers.Lock()
+	defer answers.Unlock()
+	answers.once.Do(loadAnswers)
+	_, ok := answers.byID[id]
+	return ok || len(answers.list) > 0
+}
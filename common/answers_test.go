@@ -0,0 +1,95 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// resetAnswers forces to read again the predefined answers.
+func resetAnswers() {
+	answers.Lock()
+	defer answers.Unlock()
+	answers.once, answers.list, answers.byID = sync.Once{}, nil, nil
+}
+
+func TestNextAnswer(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "answers")
+	if err := os.WriteFile(fpath, []byte("first\nedit=n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvAnswersFile, fpath)
+	t.Setenv(EnvAnswers, " approve = y ;second;;depends=a=b;3-4")
+	resetAnswers()
+	defer resetAnswers()
+
+	// The answers with id don't depend on the order of the questions,
+	// so adding a question doesn't shift them.
+	tests := []struct {
+		id, answer string
+		ok         bool
+	}{
+		{"edit", "n", true},
+		{"approve", "y", true},
+		{"skip", "first", true},
+		{"edit", "n", true},
+		{"upgrade", "second", true},
+		{"", "", true},
+		{"approve", "y", true},
+		{"depends", "a=b", true},
+		{"skip", "3-4", true},
+		{"upgrade", "", false},
+	}
+	for i, tt := range tests {
+		answer, ok := nextAnswer(tt.id)
+		if answer != tt.answer || ok != tt.ok {
+			t.Errorf("%d: nextAnswer(%q) = %q, %v, want %q, %v", i, tt.id, answer, ok, tt.answer, tt.ok)
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsTerminal(f) {
+		t.Error("a regular file is a terminal")
+	}
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	if IsTerminal(null) {
+		t.Error("/dev/null is a terminal")
+	}
+}
+
+func TestCanAnswer(t *testing.T) {
+	SetNoConfirm(true)
+	defer SetNoConfirm(false)
+	t.Setenv(EnvAnswersFile, "")
+	t.Setenv(EnvAnswers, "approve=y")
+	resetAnswers()
+	defer resetAnswers()
+
+	if !CanAnswer("approve") {
+		t.Error("keyed answer not available")
+	}
+	if CanAnswer("edit") {
+		t.Error("answer available without predefined answer")
+	}
+
+	t.Setenv(EnvAnswers, "n")
+	resetAnswers()
+	if !CanAnswer("edit") || !CanAnswer("approve") {
+		t.Error("positional answer not available")
+	}
+	nextAnswer("edit")
+	if CanAnswer("approve") {
+		t.Error("answer available after the last answer")
+	}
+}
@@ -0,0 +1,219 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+var offlineFallback atomic.Bool
+
+var offlineUsed atomic.Bool
+
+var offlineWarning sync.Once
+
+// usedCache contains the cache files of the requests of this process.
+var usedCache sync.Map
+
+// SetOfflineFallback enables or disables the offline fallback:
+// if enabled and a server cannot be reached, Request returns
+// the cached response of the URL, if any.
+// It should be enabled only for read-only commands.
+func SetOfflineFallback(enabled bool) {
+	offlineFallback.Store(enabled)
+}
+
+// OfflineFallbackUsed returns true if a cached response
+// was returned in place of the response of a server
+// which could not be reached.
+func OfflineFallbackUsed() bool {
+	return offlineUsed.Load()
+}
+
+// cacheEntry is a cached response, stored as a json file.
+type cacheEntry struct {
+	Url          string      `json:"url"`
+	ETag         string      `json:"etag"`
+	LastModified string      `json:"last_modified"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// HTTPCacheDir returns the directory of the HTTP cache,
+// or an empty string if the cache is disabled.
+func HTTPCacheDir() string {
+	if Config.Get("http.cache") == No {
+		return ""
+	}
+	if dir := Config.Get("http.cacheDir"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, fbLocaleDomain, "http")
+}
+
+// CloneCacheDir returns the directory of the persistent clones
+// of the packages, or an empty string if the cache is disabled
+// (which is the default).
+func CloneCacheDir() string {
+	if Config.Get("kcp.cloneCache") != Yes {
+		return ""
+	}
+	if dir := Config.Get("kcp.cloneCacheDir"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, fbLocaleDomain, "clones")
+}
+
+// cachePath returns the path of the cache file of the request.
+// Since the response can depend on the credentials, they are part of the key.
+func cachePath(request *http.Request) string {
+	dir := HTTPCacheDir()
+	if dir == "" {
+		return ""
+	}
+	h := sha256.New()
+	for _, s := range []string{request.URL.String(), request.Header.Get("Authorization"), request.Header.Get("PRIVATE-TOKEN")} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(dir, key[:2], key+".json")
+}
+
+func loadCache(fpath string) *cacheEntry {
+	if fpath == "" {
+		return nil
+	}
+	usedCache.Store(fpath, true)
+	b, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if json.Unmarshal(b, &entry) != nil {
+		return nil
+	}
+	return &entry
+}
+
+// saveCache stores the response if it can be validated later.
+// The file is written atomically, since multiple requests
+// can run concurrently.
+func saveCache(fpath string, entry cacheEntry) error {
+	if fpath == "" || (entry.ETag == "" && entry.LastModified == "") {
+		return nil
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(fpath), ".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), fpath)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// setConditional adds the validators of the cached response to the request.
+func (entry *cacheEntry) setConditional(request *http.Request) {
+	if entry == nil {
+		return
+	}
+	if entry.ETag != "" && request.Header.Get("If-None-Match") == "" {
+		request.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" && request.Header.Get("If-Modified-Since") == "" {
+		request.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// mergeHeader returns the cached header updated by the new header
+// (for example, the rate limit headers of a 304 response).
+func (entry *cacheEntry) mergeHeader(header http.Header) http.Header {
+	merged := entry.Header.Clone()
+	if merged == nil {
+		merged = make(http.Header)
+	}
+	for k, v := range header {
+		merged[k] = v
+	}
+	return merged
+}
+
+// fallback returns the cached response if the offline fallback is enabled.
+func (entry *cacheEntry) fallback() (*cacheEntry, bool) {
+	if entry == nil || !offlineFallback.Load() {
+		return nil, false
+	}
+	offlineUsed.Store(true)
+	offlineWarning.Do(func() {
+		PrintWarning(Tr(msgOfflineFallback))
+	})
+	return entry, true
+}
+
+// removeCache removes the cached responses for which keep returns false.
+func removeCache(keep func(fpath string) bool) (removed int, err error) {
+	dir := HTTPCacheDir()
+	if dir == "" {
+		return
+	}
+	var files []string
+	if files, err = filepath.Glob(filepath.Join(dir, "*", "*.json")); err != nil {
+		return
+	}
+	for _, f := range files {
+		if keep(f) {
+			continue
+		}
+		if err = os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return
+		}
+		removed++
+		// The directory is only removed if empty.
+		os.Remove(filepath.Dir(f))
+	}
+	return removed, nil
+}
+
+// PruneHTTPCache removes the cached responses which were not used
+// by the requests of this process (eg. the pages of the removed
+// packages or the responses for previous credentials).
+// It should only be called after a successful update of all sources.
+func PruneHTTPCache() (int, error) {
+	return removeCache(func(fpath string) bool {
+		_, ok := usedCache.Load(fpath)
+		return ok
+	})
+}
+
+// CleanHTTPCache removes all the cached responses.
+func CleanHTTPCache() (int, error) {
+	return removeCache(func(string) bool { return false })
+}
@@ -0,0 +1,82 @@
+package common
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOfflineFallback(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	defer SetOfflineFallback(false)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, "v1")
+	}))
+	ctx := context.Background()
+	if _, _, err := Request(ctx, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	// Without fallback, the error is returned.
+	if _, _, err := Request(ctx, server.URL); err == nil {
+		t.Fatal("request to a closed server succeeded")
+	}
+	if OfflineFallbackUsed() {
+		t.Error("fallback used while disabled")
+	}
+
+	SetOfflineFallback(true)
+	body, _, err := Request(ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(body); string(b) != "v1" {
+		t.Errorf("cached body: %q", b)
+	}
+	if !OfflineFallbackUsed() {
+		t.Error("fallback not tracked")
+	}
+}
+
+func TestPruneHTTPCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"`+r.URL.Path+`"`)
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	for _, p := range []string{"/a", "/b"} {
+		if _, _, err := Request(ctx, server.URL+p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A response cached by a previous run.
+	old := filepath.Join(HTTPCacheDir(), "00", "00.json")
+	if err := os.MkdirAll(filepath.Dir(old), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(old, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := PruneHTTPCache(); err != nil || n != 1 {
+		t.Errorf("PruneHTTPCache() = %d, %v", n, err)
+	}
+	if _, err := os.Stat(filepath.Dir(old)); !os.IsNotExist(err) {
+		t.Errorf("empty dir not removed: %v", err)
+	}
+	if n, err := CleanHTTPCache(); err != nil || n != 2 {
+		t.Errorf("CleanHTTPCache() = %d, %v", n, err)
+	}
+}
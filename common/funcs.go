@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -20,7 +21,14 @@ func Tr(msg string, vars ...any) string {
 
 // LaunchCommand launches a system command.
 func LaunchCommand(name string, args ...string) error {
+	return LaunchCommandIn("", name, args...)
+}
+
+// LaunchCommandIn launches a system command in the given dir.
+// If dir is empty, the command is launched in the working dir.
+func LaunchCommandIn(dir, name string, args ...string) error {
 	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
 	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
 	return cmd.Run()
 }
@@ -32,37 +40,52 @@ func GetOutputCommand(name string, args ...string) ([]byte, error) {
 }
 
 // Edit lets the user edit the given file.
+// The editor is launched in the dir of the file.
 func EditFile(f string) error {
-	return LaunchCommand(DefaultEditor, f)
-}
-
-// InstalledVersion returns the installed version of a package.
-func InstalledVersion(app string) string {
-	if b, e := GetOutputCommand("pacman", "-Q", app); e == nil {
-		f := strings.Fields(string(b))
-		if len(f) >= 2 {
-			return f[1]
-		}
-	}
-	return ""
+	return LaunchCommandIn(filepath.Dir(f), DefaultEditor, filepath.Base(f))
 }
 
 // Question displays a question to the output and returns the response given by the user.
-func Question(msg string) string {
+// If a predefined answer is available for the question id, it is used instead.
+// If the user cannot be prompted, the response is empty.
+func Question(id, msg string) string {
 	fmt.Print(msg + " ")
+	if resp, ok := nextAnswer(id); ok {
+		fmt.Println(resp)
+		return resp
+	}
+	if !Interactive() {
+		fmt.Println()
+		return ""
+	}
 	sc := bufio.NewScanner(os.Stdin)
 	sc.Scan()
 	return strings.TrimSpace(sc.Text())
 }
 
 // QuestionYN displays a question to the output and returns the boolean response given by the user.
-func QuestionYN(msg string, defaultResponse bool) bool {
+// If a predefined answer is available for the question id, it is used instead.
+// If the user cannot be prompted, the default response is returned.
+func QuestionYN(id, msg string, defaultResponse bool) bool {
 	defstr, resp := Tr(cDefaultYes), ""
 	if !defaultResponse {
 		defstr = Tr(cDefaultNo)
 	}
 	format.FormatOf("l_yellow").Printf("%s %s ", msg, defstr)
-	if _, e := fmt.Scanf("%v", &resp); e != nil || len(resp) == 0 {
+	if answer, ok := nextAnswer(id); ok {
+		fmt.Println(answer)
+		resp = answer
+	} else if !Interactive() {
+		if defaultResponse {
+			fmt.Println(Tr(Yes))
+		} else {
+			fmt.Println(Tr(No))
+		}
+		return defaultResponse
+	} else if _, e := fmt.Scanf("%v", &resp); e != nil {
+		return defaultResponse
+	}
+	if len(resp) == 0 {
 		return defaultResponse
 	}
 	yes, no := strings.ToLower(Tr(Yes)), strings.ToLower(Tr(No))
@@ -2,11 +2,83 @@ package common
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
+	"time"
 )
 
+const (
+	defaultConnectTimeout   = 10 * time.Second
+	defaultRequestTimeout   = time.Minute
+	defaultIdleConnsPerHost = 32
+)
+
+var httpClient = sync.OnceValue(newHTTPClient)
+
+// configDuration returns the duration in seconds of the given config key,
+// or the fallback if the key is not set.
+func configDuration(key string, fallback time.Duration) time.Duration {
+	if s, err := strconv.Atoi(Config.Get(key)); err == nil {
+		return time.Duration(s) * time.Second
+	}
+	return fallback
+}
+
+// newHTTPClient returns the client shared by all requests,
+// tuned with the timeouts and the proxy of the configuration.
+// If no proxy is configured, the environment variables
+// (HTTP_PROXY, HTTPS_PROXY and NO_PROXY) are used.
+func newHTTPClient() *http.Client {
+	connectTimeout := configDuration("http.connectTimeout", defaultConnectTimeout)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy := Config.Get("http.proxy"); proxy != "" {
+		if u, err := url.Parse(proxy); err == nil {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.MaxIdleConnsPerHost = defaultIdleConnsPerHost
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   configDuration("http.timeout", defaultRequestTimeout),
+	}
+}
+
+// UserAgent returns the User-Agent header sent with the requests.
+func UserAgent() string {
+	if ua := Config.Get("http.userAgent"); ua != "" {
+		return ua
+	} else if Version == "" {
+		return "kcp"
+	}
+	return "kcp/" + Version
+}
+
+// sleepContext waits for the given duration or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // BasicAuth is a structure representing a basic authentication configuration.
 type BasicAuth struct {
 	Username string
@@ -35,23 +107,47 @@ func GetAuthParameters() []string {
 	return nil
 }
 
+// HTTPError is returned by Request when the server
+// responds with an error status code.
+type HTTPError struct {
+	Url        string
+	StatusCode int
+	Status     string
+}
+
+func (e HTTPError) Error() string {
+	return Tr(errHTTPStatus, e.Url, e.Status)
+}
+
 // Request do an HTTP request on the requested URL, using the optional context configuration.
+// The request is cancelled if ctx is done.
 // It returns the body/header response, or an error if request failed.
-func Request(requestUrl string, context ...Context) (responseBody io.Reader, responseHeader http.Header, err error) {
-	var ctx Context
-	if len(context) > 0 {
-		ctx = context[0]
+// Failed requests are retried with an exponential backoff
+// if the error is temporary (timeout, server error or rate limit),
+// and the rate limit headers of the server are honoured:
+// if the quota is exhausted, the next requests wait for the reset.
+// If the response has an error status, an HTTPError is returned
+// (or a RateLimitError if the rate limit is reached).
+// The responses of GET requests are cached on disk: the next requests
+// of the same URL are conditional (ETag/Last-Modified) and the cached
+// body is reused if the server responds 304 Not Modified.
+// If the offline fallback is enabled, the cached body is also returned
+// when the server cannot be reached or still fails after the retries.
+func Request(ctx context.Context, requestUrl string, options ...Context) (responseBody io.Reader, responseHeader http.Header, err error) {
+	var opt Context
+	if len(options) > 0 {
+		opt = options[0]
 	}
 
 	method := http.MethodGet
-	if ctx.Method != "" {
-		method = ctx.Method
+	if opt.Method != "" {
+		method = opt.Method
 	}
 
-	if len(ctx.Query) > 0 {
+	if len(opt.Query) > 0 {
 		if p, e := url.Parse(requestUrl); e == nil {
 			query := p.Query()
-			for k, vv := range ctx.Query {
+			for k, vv := range opt.Query {
 				for _, v := range vv {
 					query.Add(k, v)
 				}
@@ -61,35 +157,108 @@ func Request(requestUrl string, context ...Context) (responseBody io.Reader, res
 		}
 	}
 
-	var request *http.Request
-	if request, err = http.NewRequest(method, requestUrl, ctx.Body); err != nil {
-		return
+	// The body is kept to be sent again on retries.
+	var body []byte
+	if opt.Body != nil {
+		if body, err = io.ReadAll(opt.Body); err != nil {
+			return
+		}
 	}
 
-	if ctx.BasicAuth != nil {
-		request.SetBasicAuth(ctx.BasicAuth.Username, ctx.BasicAuth.Password)
-	}
+	var cacheFile string
+	var cached *cacheEntry
+	for attempt := 0; ; attempt++ {
+		var request *http.Request
+		if request, err = http.NewRequestWithContext(ctx, method, requestUrl, bytes.NewReader(body)); err != nil {
+			return
+		}
+		request.Header.Set("User-Agent", UserAgent())
 
-	if ctx.Header != nil {
-		for k := range ctx.Header {
-			request.Header.Set(k, ctx.Header.Get(k))
+		if opt.BasicAuth != nil {
+			request.SetBasicAuth(opt.BasicAuth.Username, opt.BasicAuth.Password)
 		}
-	}
 
-	var response *http.Response
-	if response, err = new(http.Client).Do(request); err != nil {
-		return
-	}
-	defer response.Body.Close()
+		if opt.Header != nil {
+			for k := range opt.Header {
+				request.Header.Set(k, opt.Header.Get(k))
+			}
+		}
 
-	var rb *bytes.Buffer
-	if b, e := io.ReadAll(response.Body); e == nil {
-		rb = bytes.NewBuffer(b)
-	} else {
-		rb = bytes.NewBuffer([]byte{})
-	}
+		if method == http.MethodGet {
+			if attempt == 0 {
+				cacheFile = cachePath(request)
+				cached = loadCache(cacheFile)
+			}
+			cached.setConditional(request)
+		}
 
-	responseBody, responseHeader = rb, response.Header
+		host := request.URL.Host
+		if err = waitRateLimit(ctx, host); err != nil {
+			return
+		}
 
-	return
+		var response *http.Response
+		if response, err = httpClient().Do(request); err != nil {
+			var netErr net.Error
+			if attempt < maxRetries && ctx.Err() == nil && errors.As(err, &netErr) && netErr.Timeout() {
+				if err = sleepContext(ctx, backoff(attempt)); err != nil {
+					return
+				}
+				continue
+			}
+			if entry, ok := cached.fallback(); ok && ctx.Err() == nil {
+				return bytes.NewBuffer(entry.Body), entry.Header, nil
+			}
+			return
+		}
+
+		var rb *bytes.Buffer
+		if b, e := io.ReadAll(response.Body); e == nil {
+			rb = bytes.NewBuffer(b)
+		} else {
+			rb = bytes.NewBuffer([]byte{})
+		}
+		response.Body.Close()
+
+		responseBody, responseHeader = rb, response.Header
+		updateRateLimit(host, response.Header)
+		switch {
+		case response.StatusCode == http.StatusNotModified && cached != nil:
+			responseBody, responseHeader = bytes.NewBuffer(cached.Body), cached.mergeHeader(response.Header)
+			return
+		case response.StatusCode == http.StatusOK && method == http.MethodGet:
+			saveCache(cacheFile, cacheEntry{
+				Url:          requestUrl,
+				ETag:         response.Header.Get("ETag"),
+				LastModified: response.Header.Get("Last-Modified"),
+				Header:       response.Header,
+				Body:         rb.Bytes(),
+			})
+			return
+		case response.StatusCode < http.StatusBadRequest:
+			return
+		}
+
+		wait, retry, e := retryDelay(host, response, attempt)
+		if !retry {
+			err = e
+			if err == nil {
+				err = HTTPError{
+					Url:        requestUrl,
+					StatusCode: response.StatusCode,
+					Status:     response.Status,
+				}
+			}
+			// The server is still failing: it is as unreachable.
+			if response.StatusCode >= http.StatusInternalServerError && ctx.Err() == nil {
+				if entry, ok := cached.fallback(); ok {
+					return bytes.NewBuffer(entry.Body), entry.Header, nil
+				}
+			}
+			return
+		}
+		if err = sleepContext(ctx, wait); err != nil {
+			return
+		}
+	}
 }
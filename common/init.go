@@ -30,6 +30,7 @@ var (
 
 	GitDomain    string
 	Organization string
+	GitConnector string
 	User         string
 	Password     string
 	Token        string
@@ -176,6 +177,9 @@ func init() {
 	if org != "" {
 		Organization = org
 	}
+	if connector := Config.Get("git.connector"); connector != "" {
+		GitConnector = connector
+	}
 	if token != "" {
 		Token = token
 	} else if user != "" && passwd != "" {
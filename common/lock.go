@@ -0,0 +1,127 @@
+package common
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const lockPollInterval = 200 * time.Millisecond
+
+// LockedError is returned when the lock is held by another process.
+type LockedError struct {
+	Path string
+	Pid  int
+}
+
+func (e LockedError) Error() string {
+	if e.Pid > 0 {
+		return Tr(errLockedBy, e.Pid, e.Path)
+	}
+	return Tr(errLocked, e.Path)
+}
+
+// Lock is an exclusive lock between processes, based on flock.
+// The lock file contains the PID of the owner.
+// Since the lock is released by the system when the owner dies,
+// a remaining lock file without lock is considered as stale
+// and is taken over.
+type Lock struct {
+	path string
+	f    *os.File
+
+	// StalePid is the PID of the previous owner
+	// if a stale lock file was found, or 0.
+	StalePid int
+}
+
+func readLockPid(f *os.File) int {
+	b := make([]byte, 32)
+	n, _ := f.ReadAt(b, 0)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b[:n])))
+	return pid
+}
+
+func sameFile(f *os.File, path string) bool {
+	i1, err1 := f.Stat()
+	i2, err2 := os.Stat(path)
+	return err1 == nil && err2 == nil && os.SameFile(i1, i2)
+}
+
+// TryLock tries to acquire the lock on the given file
+// and fails at once if it is held by another process.
+func TryLock(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			pid := readLockPid(f)
+			f.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, LockedError{Path: path, Pid: pid}
+			}
+			return nil, err
+		}
+
+		// The file may have been removed by the previous owner
+		// between the opening and the lock, so retry.
+		if !sameFile(f, path) {
+			f.Close()
+			continue
+		}
+
+		l := &Lock{path: path, f: f}
+		if pid := readLockPid(f); pid > 0 && pid != os.Getpid() {
+			l.StalePid = pid
+		}
+		if err = f.Truncate(0); err == nil {
+			_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+		}
+		if err != nil {
+			l.Unlock()
+			return nil, err
+		}
+		return l, nil
+	}
+}
+
+// AcquireLock acquires the lock on the given file.
+// If the lock is held by another process, it waits until
+// the lock is released or the timeout expires.
+// A null timeout fails at once and a negative timeout waits forever.
+func AcquireLock(path string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := TryLock(path)
+		var e LockedError
+		if err == nil || !errors.As(err, &e) || (timeout >= 0 && !time.Now().Before(deadline)) {
+			return l, err
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// Unlock removes the lock file and releases the lock.
+func (l *Lock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := os.Remove(l.path)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	if e := l.f.Close(); err == nil {
+		err = e
+	}
+	l.f = nil
+	return err
+}
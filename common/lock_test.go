@@ -0,0 +1,87 @@
+package common
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "locked")
+
+	l, err := TryLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); strings.TrimSpace(string(b)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("lock file contains %q", b)
+	}
+
+	// flock locks are held by the open files, so a second lock
+	// fails even in the same process.
+	_, err = TryLock(path)
+	var e LockedError
+	if !errors.As(err, &e) || e.Pid != os.Getpid() || e.Path != path {
+		t.Fatalf("second TryLock: %v", err)
+	}
+
+	if err = l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file not removed: %v", err)
+	}
+	if err = l.Unlock(); err != nil {
+		t.Errorf("second Unlock: %v", err)
+	}
+
+	l, err = TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock after Unlock: %v", err)
+	}
+	l.Unlock()
+}
+
+func TestTryLockStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locked")
+	if err := os.WriteFile(path, []byte("999999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := TryLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Unlock()
+	if l.StalePid != 999999 {
+		t.Errorf("StalePid = %d", l.StalePid)
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locked")
+	l, err := TryLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A null timeout fails at once.
+	if _, err = AcquireLock(path, 0); !errors.As(err, new(LockedError)) {
+		t.Fatalf("AcquireLock without timeout: %v", err)
+	}
+
+	// A negative timeout waits for the release.
+	go func() {
+		time.Sleep(2 * lockPollInterval)
+		l.Unlock()
+	}()
+	l2, err := AcquireLock(path, -1)
+	if err != nil {
+		t.Fatalf("AcquireLock waiting forever: %v", err)
+	}
+	l2.Unlock()
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+
+	"codeberg.org/bvaudour/kcp/alpm"
+)
+
+var syncDatabases = sync.OnceValues(func() (alpm.Databases, error) {
+	// The repos are searched in the order of pacman.conf, like pacman does.
+	// If it cannot be read, all the sync databases are loaded.
+	repos, _ := alpm.LoadRepos(alpm.DefaultConfPath)
+	return alpm.LoadSyncDatabases(PacmanDBPath(), repos...)
+})
+
+var localDatabase struct {
+	sync.Mutex
+	db  *alpm.Database
+	err error
+}
+
+// PacmanDBPath returns the path of the pacman databases.
+func PacmanDBPath() string {
+	if p := Config.Get("pacman.dbPath"); p != "" {
+		return p
+	}
+	return alpm.DefaultDBPath
+}
+
+// SyncDatabases returns the sync databases of pacman.
+// They are read once, at the first call.
+func SyncDatabases() (alpm.Databases, error) {
+	return syncDatabases()
+}
+
+// LocalDatabase returns the database of the installed packages.
+// It is read once, at the first call, until ResetLocalDatabase is called.
+func LocalDatabase() (*alpm.Database, error) {
+	localDatabase.Lock()
+	defer localDatabase.Unlock()
+	if localDatabase.db == nil && localDatabase.err == nil {
+		localDatabase.db, localDatabase.err = alpm.LoadLocalDatabase(PacmanDBPath())
+	}
+	return localDatabase.db, localDatabase.err
+}
+
+// ResetLocalDatabase forces to read again the database
+// of the installed packages at the next call of LocalDatabase.
+// It should be called after installing or removing packages.
+func ResetLocalDatabase() {
+	localDatabase.Lock()
+	defer localDatabase.Unlock()
+	localDatabase.db, localDatabase.err = nil, nil
+}
+
+// InstalledVersion returns the installed version of a package.
+// If the local database cannot be read, it falls back to pacman.
+func InstalledVersion(app string) string {
+	if db, err := LocalDatabase(); err == nil {
+		if p, ok := db.Get(app); ok {
+			return p.Version
+		}
+		return ""
+	}
+	if b, e := GetOutputCommand("pacman", "-Q", app); e == nil {
+		f := strings.Fields(string(b))
+		if len(f) >= 2 {
+			return f[1]
+		}
+	}
+	return ""
+}
+
+// IsInRepo checks if a package with the given name
+// (or a package which provides it) is available on the official repos.
+// If the sync databases cannot be read, it falls back to pacman.
+func IsInRepo(app string) bool {
+	if dbs, err := SyncDatabases(); err == nil {
+		return dbs.Contains(app)
+	}
+	b, _ := GetOutputCommand("pacman", "-Si", app)
+	return len(b) > 0
+}
+
+// IsDependSatisfied checks if the given depend
+// (with an optional version constraint) is satisfied
+// by the installed packages.
+// If the local database cannot be read, it falls back to pacman.
+func IsDependSatisfied(depend string) bool {
+	if db, err := LocalDatabase(); err == nil {
+		return db.Satisfies(depend)
+	}
+	return exec.Command("pacman", "-T", depend).Run() == nil
+}
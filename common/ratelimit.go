@@ -0,0 +1,172 @@
+package common
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxRetries    = 4
+	retryBaseWait = time.Second
+	maxRetryWait  = time.Minute
+)
+
+// RateLimit is the state of the rate limit of a server,
+// as given by the last response headers.
+type RateLimit struct {
+	Host      string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func (rl RateLimit) String() string {
+	return Tr(msgRateLimit, rl.Host, rl.Remaining, rl.Limit, rl.Reset.Local().Format(time.DateTime))
+}
+
+// RateLimitError is returned by Request when the rate limit
+// of the server is reached and the reset is too far to wait.
+type RateLimitError struct {
+	RateLimit
+}
+
+func (e RateLimitError) Error() string {
+	return Tr(errRateLimit, e.RateLimit.String())
+}
+
+var rateLimits = struct {
+	sync.Mutex
+	hosts map[string]*RateLimit
+}{hosts: make(map[string]*RateLimit)}
+
+// RateLimits returns the known rate limits of the requested servers.
+func RateLimits() []RateLimit {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	out := make([]RateLimit, 0, len(rateLimits.hosts))
+	for _, rl := range rateLimits.hosts {
+		out = append(out, *rl)
+	}
+	slices.SortFunc(out, func(rl1, rl2 RateLimit) int { return strings.Compare(rl1.Host, rl2.Host) })
+	return out
+}
+
+func headerInt(header http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if v := header.Get(name); v != "" {
+			if i, err := strconv.Atoi(v); err == nil {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// parseRateLimit reads the rate limit headers (Github and Gitlab styles).
+func parseRateLimit(host string, header http.Header) (rl RateLimit, ok bool) {
+	rl.Host = host
+	if rl.Remaining, ok = headerInt(header, "X-RateLimit-Remaining", "RateLimit-Remaining"); !ok {
+		return
+	}
+	rl.Limit, _ = headerInt(header, "X-RateLimit-Limit", "RateLimit-Limit")
+	if reset, ok := headerInt(header, "X-RateLimit-Reset", "RateLimit-Reset"); ok {
+		rl.Reset = time.Unix(int64(reset), 0)
+	}
+	return
+}
+
+// updateRateLimit records the rate limit of the host from the response headers.
+func updateRateLimit(host string, header http.Header) {
+	rl, ok := parseRateLimit(host, header)
+	if !ok {
+		return
+	}
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	rateLimits.hosts[host] = &rl
+}
+
+// waitRateLimit is called before each request. It consumes one request
+// of the known quota of the host. If the quota is exhausted, it waits
+// for the reset, or fails if the reset is too far.
+func waitRateLimit(ctx context.Context, host string) error {
+	rateLimits.Lock()
+	rl, ok := rateLimits.hosts[host]
+	if !ok {
+		rateLimits.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if !now.Before(rl.Reset) {
+		delete(rateLimits.hosts, host)
+		rateLimits.Unlock()
+		return nil
+	}
+	if rl.Remaining > 0 {
+		rl.Remaining--
+		rateLimits.Unlock()
+		return nil
+	}
+	current := *rl
+	rateLimits.Unlock()
+
+	wait := current.Reset.Sub(now)
+	if wait > maxRetryWait {
+		return RateLimitError{current}
+	}
+	return sleepContext(ctx, wait)
+}
+
+// parseRetryAfter reads the Retry-After header,
+// given either in seconds or as an HTTP date.
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// backoff returns the exponential wait before the given retry, with jitter.
+func backoff(attempt int) time.Duration {
+	wait := retryBaseWait << attempt
+	return wait + rand.N(wait/2+1)
+}
+
+// retryDelay checks if a failed response should be retried
+// and returns the time to wait before the retry.
+// If the response failed because of the rate limit and cannot
+// be retried, a RateLimitError is returned.
+func retryDelay(host string, response *http.Response, attempt int) (wait time.Duration, retry bool, err error) {
+	rl, hasRateLimit := parseRateLimit(host, response.Header)
+	limited := response.StatusCode == http.StatusTooManyRequests ||
+		(response.StatusCode == http.StatusForbidden && hasRateLimit && rl.Remaining == 0)
+	if !limited && response.StatusCode < http.StatusInternalServerError {
+		return
+	}
+
+	var ok bool
+	if wait, ok = parseRetryAfter(response.Header); !ok && limited && !rl.Reset.IsZero() {
+		wait, ok = time.Until(rl.Reset), true
+	}
+	if !ok {
+		wait = backoff(attempt)
+	}
+
+	if retry = attempt < maxRetries && wait <= maxRetryWait; !retry && limited {
+		err = RateLimitError{rl}
+	}
+	return max(wait, 0), retry, err
+}
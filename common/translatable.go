@@ -6,4 +6,13 @@ const (
 
 	Yes = "yes"
 	No  = "no"
+
+	errLocked   = "Another instance is running (locker: %s)!"
+	errLockedBy = "Another instance is running with PID %d (locker: %s)!"
+
+	errHTTPStatus = "Request to %s failed: %s"
+	errRateLimit  = "API rate limit reached (%s)"
+	msgRateLimit  = "%s: %d/%d requests remaining, reset at %s"
+
+	msgOfflineFallback = "Server unreachable: using cached responses, which may be outdated."
 )
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"codeberg.org/bvaudour/kcp/common"
+)
+
+// BuiltRef is the reference of the last built revision
+// in the persistent clone of a package.
+const BuiltRef = "refs/kcp/built"
+
+// FetchError is returned when the persistent clone of a package
+// cannot be updated. The clone is still usable but can be outdated.
+type FetchError struct {
+	Name string
+	Err  error
+}
+
+func (e FetchError) Error() string {
+	return common.Tr(errFetch, e.Name, e.Err.Error())
+}
+
+func (e FetchError) Unwrap() error {
+	return e.Err
+}
+
+func isClone(dir string) bool {
+	return common.FileExists(filepath.Join(dir, ".git"))
+}
+
+// Sync creates or updates the persistent clone of the package
+// in the given cache dir and returns the path of the clone.
+// If the clone already exists, the remote changes are fetched and the clone
+// is reset to the remote HEAD, so the local changes of the tracked files are lost.
+// The untracked files (like the downloaded sources) are kept.
+// If the fetch failed (eg. offline), the clone is kept at its revision
+// and a FetchError is returned.
+// if ssh it clones using ssh. The progress of the clone is written to progress.
+func (p Package) Sync(ctx context.Context, cacheDir string, ssh bool, progress io.Writer) (fullDir string, err error) {
+	fullDir = filepath.Join(cacheDir, p.Name)
+	url := p.CloneUrl
+	if ssh {
+		url = p.SshUrl
+	}
+
+	if !isClone(fullDir) {
+		if err = os.RemoveAll(fullDir); err != nil {
+			return
+		}
+		return p.Clone(ctx, CloneOptions{
+			Dir:      fullDir,
+			Ssh:      ssh,
+			Progress: progress,
+		})
+	}
+
+	if _, err = git(ctx, fullDir, "remote", "set-url", "origin", url); err != nil {
+		return
+	}
+	if _, e := git(ctx, fullDir, "fetch", "--quiet", "origin", "HEAD"); e != nil {
+		if _, err = git(ctx, fullDir, "reset", "--quiet", "--hard"); err == nil {
+			err = FetchError{Name: p.Name, Err: e}
+		}
+		return
+	}
+	_, err = git(ctx, fullDir, "reset", "--quiet", "--hard", "FETCH_HEAD")
+
+	return
+}
+
+// MarkBuilt records the current revision of the clone
+// as the last built revision (see BuiltRef).
+func MarkBuilt(ctx context.Context, dir string) error {
+	_, err := git(ctx, dir, "update-ref", BuiltRef, "HEAD")
+	return err
+}
+
+// BuiltLog returns the commits of the clone since the last built
+// revision (see BuiltRef), one per line, or an empty string
+// if the package was never built in this clone.
+func BuiltLog(ctx context.Context, dir string) (string, error) {
+	if _, err := git(ctx, dir, "rev-parse", "--verify", "--quiet", BuiltRef); err != nil {
+		return "", nil
+	}
+	return git(ctx, dir, "log", "--format=%h %s (%an, %cs)", BuiltRef+"..HEAD")
+}
+
+// CleanClones removes the persistent clones of the cache dir
+// whose packages are not installed or don’t exist anymore,
+// and returns the names of the removed clones.
+func (db Database) CleanClones(cacheDir string) (removed []string, err error) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if p, ok := db.Get(name); ok && FilterInstalled(p) {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(cacheDir, name)); err != nil {
+			return
+		}
+		removed = append(removed, name)
+	}
+
+	return
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"codeberg.org/bvaudour/kcp/common"
+)
+
+var rCommit = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// CloneOptions are the options to clone the git repo of a package.
+type CloneOptions struct {
+	// Dir is the directory of the clone.
+	// If empty, it is the subdirectory of the current dir named like the package.
+	Dir string
+
+	// Ref is the branch, the tag or the commit to check out.
+	// If empty, the default branch is checked out.
+	Ref string
+
+	// Depth is the number of commits to fetch (shallow clone).
+	// If 0, the full history is fetched.
+	// A commit must be complete to be used with a shallow clone.
+	Depth int
+
+	// Ssh tells to clone using ssh.
+	Ssh bool
+
+	// Progress receives the progress of the clone.
+	// If nil, the clone is quiet.
+	Progress io.Writer
+}
+
+// verbosity returns the flag to display the progress of git or to make it quiet.
+func (opts CloneOptions) verbosity() string {
+	if opts.Progress != nil {
+		return "--progress"
+	}
+	return "--quiet"
+}
+
+// git launches a git command in the given dir.
+// If the output isn’t redirected to Progress, the error
+// contains the message of git.
+func (opts CloneOptions) git(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if opts.Progress != nil {
+		cmd.Stderr = opts.Progress
+	}
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func isEmptyDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err == nil && len(entries) == 0
+}
+
+// Clone clones the git repo corresponding to the package
+// with the given options and returns the directory of the clone.
+// Contrary to a git clone launched from the current dir,
+// it doesn’t change the working dir of the process, so it can
+// be used concurrently. The clone can be cancelled or limited
+// in time through the context.
+func (p Package) Clone(ctx context.Context, opts CloneOptions) (fullDir string, err error) {
+	fullDir = opts.Dir
+	if fullDir == "" {
+		fullDir = p.Name
+	}
+	if fullDir, err = filepath.Abs(fullDir); err != nil {
+		return
+	}
+	created := !common.FileExists(fullDir)
+	if !created && !isEmptyDir(fullDir) {
+		err = errors.New(common.Tr(errPathExists, fullDir))
+		return
+	}
+
+	url := p.CloneUrl
+	if opts.Ssh {
+		url = p.SshUrl
+	}
+	if opts.Depth > 0 && filepath.IsAbs(url) {
+		// Shallow clones are ignored for local paths.
+		url = "file://" + url
+	}
+	flags := []string{opts.verbosity()}
+	if opts.Depth > 0 {
+		flags = append(flags, "--depth", strconv.Itoa(opts.Depth))
+	}
+
+	if err = os.MkdirAll(fullDir, 0755); err != nil {
+		return
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		err = errors.New(common.Tr(errClone, p.Name, err.Error()))
+		if created {
+			os.RemoveAll(fullDir)
+		} else {
+			clearDir(fullDir)
+		}
+	}()
+
+	switch {
+	case opts.Ref == "":
+		err = opts.git(ctx, fullDir, slices.Concat([]string{"clone"}, flags, []string{url, "."})...)
+	case !rCommit.MatchString(opts.Ref):
+		err = opts.git(ctx, fullDir, slices.Concat([]string{"clone", "--branch", opts.Ref}, flags, []string{url, "."})...)
+	case opts.Depth == 0:
+		if err = opts.git(ctx, fullDir, "clone", opts.verbosity(), "--no-checkout", url, "."); err == nil {
+			err = opts.git(ctx, fullDir, "checkout", "--quiet", opts.Ref)
+		}
+	default:
+		// A commit cannot be cloned directly with a limited depth,
+		// so it is fetched in a new repo.
+		for _, args := range [][]string{
+			{"init", "--quiet"},
+			{"remote", "add", "origin", url},
+			slices.Concat([]string{"fetch"}, flags, []string{"origin", opts.Ref}),
+			{"checkout", "--quiet", "FETCH_HEAD"},
+		} {
+			if err = opts.git(ctx, fullDir, args...); err != nil {
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// clearDir removes the content of the dir.
+func clearDir(dir string) {
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		os.RemoveAll(filepath.Join(dir, e.Name()))
+	}
+}
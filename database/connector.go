@@ -1,22 +1,72 @@
 package database
 
 import (
-	"codeberg.org/bvaudour/kcp/common"
+	"context"
+	"io"
+	"strings"
+
+	"codeberg.org/bvaudour/kcp/pkgbuild"
 )
 
 // Connector is an interface which defines
 // the way to implement a git API server to
 // get the repositories' list of an organization.
 type Connector interface {
-	CountPublcRepos() (int, error)
-	GetPage(page, limit int) ([]Package, error)
+	CountPublcRepos(ctx context.Context) (int, error)
+	GetPage(ctx context.Context, page, limit int) ([]Package, error)
 }
 
-// NewConnector returns the connector according to the configuration.
-func NewConnector() Connector {
-	auth := common.GetAuthParameters()
-	if common.GitDomain == "github.com" || common.GitDomain == "" {
-		return NewGithubConnector(common.Organization, auth...)
+// PKGBUILDReader is an optional interface for the connectors
+// which can read the PKGBUILD of a package by themselves,
+// instead of downloading it from the PKGBUILD URL.
+type PKGBUILDReader interface {
+	ReadPKGBUILD(ctx context.Context, p Package) (io.Reader, error)
+}
+
+// getPKGBUILD reads the PKGBUILD of the package
+// using the connector if possible.
+func getPKGBUILD(ctx context.Context, connector Connector, p Package, debug bool) (*pkgbuild.PKGBUILD, error) {
+	r, ok := connector.(PKGBUILDReader)
+	if !ok {
+		return p.GetPKGBUID(ctx, debug)
+	}
+	body, err := r.ReadPKGBUILD(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return pkgbuild.DecodeVars(body)
+}
+
+// Types of connectors
+const (
+	ConnectorGithub  = "github"
+	ConnectorForgejo = "forgejo"
+	ConnectorGitlab  = "gitlab"
+	ConnectorLocal   = "local"
+)
+
+// ConnectorType returns the type of connector to use for the given domain.
+// If connectorType is not empty, it is returned as is. Otherwise,
+// the type is guessed from the domain: github for github.com (or an empty domain),
+// local for an absolute path, gitlab for gitlab.com or any domain
+// containing "gitlab", forgejo otherwise.
+func ConnectorType(domain, connectorType string) string {
+	if connectorType != "" {
+		return strings.ToLower(connectorType)
+	}
+	switch {
+	case domain == "" || domain == "github.com":
+		return ConnectorGithub
+	case strings.HasPrefix(domain, "/"):
+		return ConnectorLocal
+	case strings.Contains(domain, "gitlab"):
+		return ConnectorGitlab
 	}
-	return NewForgejoConnector(common.GitDomain, common.Organization, auth...)
+	return ConnectorForgejo
+}
+
+// NewConnector returns the connector of the default source,
+// according to the configuration.
+func NewConnector() Connector {
+	return defaultSource().NewConnector()
 }
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"io"
 	"log"
-	"os"
 	"slices"
 	"sync"
 	"time"
@@ -16,16 +15,19 @@ import (
 // Database is the decoded structure
 // of a json database of packages.
 type Database struct {
+	Version       int       `json:"version"`
 	LastUpdate    time.Time `json:"last_update"`
 	IgnoreRepos   []string  `json:"ignore_repos"`
 	BrokenDepends []string  `json:"broken_depends"`
 	Packages      `json:"packages"`
+	Hidden        Packages `json:"hidden_packages"`
 }
 
 // New returns a new empty database initialized
 // by repositories of the organzation to ignore.
 func New(ignored ...string) Database {
 	return Database{
+		Version:     SchemaVersion,
 		IgnoreRepos: ignored,
 	}
 }
@@ -43,40 +45,22 @@ func (db Database) Encode(w io.Writer) error {
 	return enc.Encode(db)
 }
 
-// Load decodes the file in the given path and
-// returns the decoded database.
-func Load(fpath string, ignored ...string) (db Database, err error) {
-	var file *os.File
-	if file, err = os.Open(fpath); err != nil {
-		return
-	}
-	defer file.Close()
-
-	db = New(ignored...)
-	db.Decode(file)
-
-	return
-}
-
-// Save writes the database into the file on the given path.
-func Save(fpath string, db Database) error {
-	file, err := os.Create(fpath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	return db.Encode(file)
-}
-
 // UpdateBroken updates the broken depends.
 func (db *Database) UpdateBroken() {
 	db.BrokenDepends = db.Packages.SearchBroken()
 }
 
+// UpdateLocal updates the installed versions of the packages.
+func (db *Database) UpdateLocal() {
+	for i, p := range db.Packages {
+		db.Packages[i].LocalVersion = p.GetLocaleVersion()
+	}
+}
+
 // UpdateRemote updates the database from the remote server.
 // It returns a counter of the changes.
-func (db *Database) UpdateRemote(connector Connector, debug bool) (counter Counter, err error) {
+// The update is stopped if the context is cancelled.
+func (db *Database) UpdateRemote(parent context.Context, connector Connector, debug bool) (counter Counter, err error) {
 	// Étape 7: Mettre à jour db.LastUpdate avec la date/heure du début du traitement.
 	startTime := time.Now()
 	defer func() {
@@ -86,7 +70,7 @@ func (db *Database) UpdateRemote(connector Connector, debug bool) (counter Count
 	}()
 
 	// Étape 1: Évaluer le nombre d'appels nécessaires.
-	count, err := connector.CountPublcRepos()
+	count, err := connector.CountPublcRepos(parent)
 	if err != nil {
 		return counter, err
 	}
@@ -95,7 +79,7 @@ func (db *Database) UpdateRemote(connector Connector, debug bool) (counter Count
 		pages++
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -120,7 +104,7 @@ func (db *Database) UpdateRemote(connector Connector, debug bool) (counter Count
 			default:
 			}
 
-			pagePackages, pageErr := connector.GetPage(page, defaultLimit)
+			pagePackages, pageErr := connector.GetPage(ctx, page, defaultLimit)
 			if pageErr != nil {
 				errChan <- pageErr
 				cancel() // Annuler toutes les autres goroutines
@@ -151,7 +135,7 @@ func (db *Database) UpdateRemote(connector Connector, debug bool) (counter Count
 					pkg.noChange = true
 					// 4.2. Vérifier si le paquet a été mis à jour.
 					if pkg.UpdatedAt.After(db.LastUpdate) {
-						if file, err := pkg.GetPKGBUID(debug); err == nil {
+						if file, err := getPKGBUILD(ctx, connector, pkg, debug); err == nil {
 							pkg.updateFromPKGBUILD(file)
 							pkg.noChange = false
 						} else if debug {
@@ -183,6 +167,11 @@ func (db *Database) UpdateRemote(connector Connector, debug bool) (counter Count
 		return counter, err
 	}
 
+	// Le traitement a pu être interrompu sans erreur des goroutines.
+	if err = parent.Err(); err != nil {
+		return counter, err
+	}
+
 	// Étape 5: Parcourir la liste des paquets à traiter.
 	remotePackages := Packages(stack.CloseData())
 	newPackages := make(Packages, 0, len(remotePackages))
@@ -193,7 +182,7 @@ func (db *Database) UpdateRemote(connector Connector, debug bool) (counter Count
 			// 5.1. Le paquet n'existe pas dans la base de données.
 			counter.Added++
 			if p.noChange {
-				if file, err := p.GetPKGBUID(debug); err == nil {
+				if file, err := getPKGBUILD(ctx, connector, p, debug); err == nil {
 					p.updateFromPKGBUILD(file)
 				} else if debug {
 					log.Printf(errFailedGetPKGBUILDForNewPackage, p.Name, err)
@@ -229,8 +218,8 @@ func (db *Database) UpdateRemote(connector Connector, debug bool) (counter Count
 }
 
 // Update checks if updates are available in the database.
-func (db *Database) Update(connector Connector, debug bool) (counter Counter, err error) {
-	if counter, err = db.UpdateRemote(connector, debug); err == nil {
+func (db *Database) Update(ctx context.Context, connector Connector, debug bool) (counter Counter, err error) {
+	if counter, err = db.UpdateRemote(ctx, connector, debug); err == nil {
 		db.UpdateBroken()
 	}
 
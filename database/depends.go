@@ -0,0 +1,170 @@
+package database
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"codeberg.org/bvaudour/kcp/alpm"
+	"codeberg.org/bvaudour/kcp/common"
+)
+
+// isSatisfiedOutside checks if the depend is already installed
+// or if it is available on the official repos.
+func isSatisfiedOutside(d string) bool {
+	return common.IsDependSatisfied(d) || common.IsInRepo(alpm.DependName(d))
+}
+
+// Provider returns the package which provides the given depend.
+// A package whose name matches is preferred to a package
+// which declares it in its provides.
+func (pl Packages) Provider(d string) (result Package, ok bool) {
+	name := alpm.DependName(d)
+	if result, ok = pl.Get(name); ok {
+		return
+	}
+	for _, p := range pl {
+		if slices.ContainsFunc(p.Provides, func(e string) bool { return alpm.DependName(e) == name }) {
+			return p, true
+		}
+	}
+	return
+}
+
+// ResolveDepends returns the list of packages to install
+// in order to install the package with the given name.
+// The list is sorted in install order, so the requested package
+// is the last entry and the previous ones are its missing
+// dependencies (depends and make depends) which are only available in KCP.
+// It returns an error if a dependency cycle is found or if
+// some dependencies are neither in the official repos nor in KCP.
+func (pl Packages) ResolveDepends(name string) (result Packages, err error) {
+	p, ok := pl.Get(name)
+	if !ok {
+		return nil, errors.New(common.Tr(errPackageNotFound, name))
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int)
+	satisfied := make(map[string]bool)
+	missing := make(map[string][]string)
+	var path []string
+
+	var visit func(Package) error
+	visit = func(p Package) error {
+		switch state[p.Name] {
+		case visited:
+			return nil
+		case visiting:
+			i := slices.Index(path, p.Name)
+			cycle := append(slices.Clone(path[i:]), p.Name)
+			return errors.New(common.Tr(errDependsCycle, strings.Join(cycle, " -> ")))
+		}
+		state[p.Name] = visiting
+		path = append(path, p.Name)
+
+		for _, d := range slices.Concat(p.Depends, p.MakeDepends) {
+			n := alpm.DependName(d)
+			if n == "" || n == p.Name {
+				continue
+			}
+			s, ok := satisfied[d]
+			if !ok {
+				s = isSatisfiedOutside(d)
+				satisfied[d] = s
+			}
+			if s {
+				continue
+			}
+			dep, ok := pl.Provider(d)
+			if !ok {
+				missing[p.Name] = append(missing[p.Name], d)
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[p.Name] = visited
+		result.Push(p)
+		return nil
+	}
+
+	if err = visit(p); err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		var errs []error
+		for _, p := range result {
+			if m, ok := missing[p.Name]; ok {
+				errs = append(errs, errors.New(common.Tr(errMissingDepends, p.Name, strings.Join(m, ", "))))
+			}
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	return
+}
+
+// ReverseDepend describes a reference to a package
+// in the metadata of another package.
+type ReverseDepend struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Field  string `json:"field"`
+	Entry  string `json:"entry"`
+}
+
+var reverseFields = []struct {
+	name string
+	get  func(Package) []string
+}{
+	{"depends", func(p Package) []string { return p.Depends }},
+	{"make_depends", func(p Package) []string { return p.MakeDepends }},
+	{"opt_depends", func(p Package) []string { return p.OptDepends }},
+	{"provides", func(p Package) []string { return p.Provides }},
+}
+
+// Label returns the translated label of the field
+// where the reference was found.
+func (r ReverseDepend) Label() string {
+	switch r.Field {
+	case "depends":
+		return common.Tr(labelDepends)
+	case "make_depends":
+		return common.Tr(labelMakeDepends)
+	case "opt_depends":
+		return common.Tr(labelOptDepends)
+	case "provides":
+		return common.Tr(labelProvides)
+	}
+	return r.Field
+}
+
+// ReverseDepends returns the references to the given name
+// found in the depends, make depends, optional depends
+// and provides of the packages of the list.
+// Entries keep their version constraint or description.
+func (pl Packages) ReverseDepends(name string) (result []ReverseDepend) {
+	for _, p := range pl {
+		for _, f := range reverseFields {
+			for _, e := range f.get(p) {
+				if alpm.DependName(e) == name {
+					result = append(result, ReverseDepend{
+						Name:   p.Name,
+						Source: p.SourceName(),
+						Field:  f.name,
+						Entry:  e,
+					})
+				}
+			}
+		}
+	}
+	return
+}
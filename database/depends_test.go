@@ -0,0 +1,71 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+// The depends of the tests use unlikely names, so they are
+// neither installed nor available in the official repos.
+var dependsPackages = Packages{
+	{Name: "kcptest-app", Depends: []string{"kcptest-lib>=1.0", "kcptest-virtual"}, MakeDepends: []string{"kcptest-tool"}},
+	{Name: "kcptest-lib", Depends: []string{"kcptest-base"}},
+	{Name: "kcptest-impl", Depends: []string{"kcptest-base"}, Provides: []string{"kcptest-virtual=2"}},
+	{Name: "kcptest-tool", OptDepends: []string{"kcptest-lib: for the plugins"}},
+	{Name: "kcptest-base"},
+	{Name: "kcptest-cycle1", Depends: []string{"kcptest-cycle2"}},
+	{Name: "kcptest-cycle2", Depends: []string{"kcptest-cycle1"}},
+	{Name: "kcptest-broken", Depends: []string{"kcptest-lib", "kcptest-missing"}},
+}
+
+func TestProvider(t *testing.T) {
+	tests := []struct {
+		depend string
+		name   string
+	}{
+		{"kcptest-lib", "kcptest-lib"},
+		{"kcptest-lib>=1.0", "kcptest-lib"},
+		{"kcptest-virtual", "kcptest-impl"},
+		{"kcptest-missing", ""},
+	}
+
+	for _, tt := range tests {
+		p, ok := dependsPackages.Provider(tt.depend)
+		if ok != (tt.name != "") || p.Name != tt.name {
+			t.Errorf("Provider(%q) = %q, %v, want %q", tt.depend, p.Name, ok, tt.name)
+		}
+	}
+}
+
+func TestResolveDepends(t *testing.T) {
+	l, err := dependsPackages.ResolveDepends("kcptest-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"kcptest-base", "kcptest-lib", "kcptest-impl", "kcptest-tool", "kcptest-app"}
+	if names := l.Names(); !slices.Equal(names, want) {
+		t.Errorf("ResolveDepends(kcptest-app) = %v, want %v", names, want)
+	}
+
+	for _, name := range []string{"kcptest-cycle1", "kcptest-broken", "kcptest-unknown"} {
+		if _, err := dependsPackages.ResolveDepends(name); err == nil {
+			t.Errorf("ResolveDepends(%s): no error", name)
+		}
+	}
+}
+
+func TestReverseDepends(t *testing.T) {
+	result := dependsPackages.ReverseDepends("kcptest-lib")
+	want := []ReverseDepend{
+		{Name: "kcptest-app", Source: DefaultSource, Field: "depends", Entry: "kcptest-lib>=1.0"},
+		{Name: "kcptest-tool", Source: DefaultSource, Field: "opt_depends", Entry: "kcptest-lib: for the plugins"},
+		{Name: "kcptest-broken", Source: DefaultSource, Field: "depends", Entry: "kcptest-lib"},
+	}
+	if !slices.Equal(result, want) {
+		t.Errorf("ReverseDepends(kcptest-lib) = %v, want %v", result, want)
+	}
+
+	if result := dependsPackages.ReverseDepends("kcptest-virtual"); len(result) != 2 {
+		t.Errorf("ReverseDepends(kcptest-virtual) = %v", result)
+	}
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"codeberg.org/bvaudour/kcp/common"
+)
+
+// SchemaVersion is the version of the database format.
+// It must be incremented (and a migration must be added)
+// each time a change of the structure needs to update
+// the existing databases.
+const SchemaVersion = 2
+
+// Migration updates a database decoded from
+// the previous version of the schema.
+type Migration func(db *Database) error
+
+// migrations[i] migrates a database from version i to version i+1.
+var migrations = []Migration{
+	// 0 -> 1: version field added, nothing else to do.
+	func(db *Database) error { return nil },
+	// 1 -> 2: source of the packages added.
+	func(db *Database) error {
+		for i := range db.Packages {
+			if db.Packages[i].Source == "" {
+				db.Packages[i].Source = DefaultSource
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	if len(migrations) != SchemaVersion {
+		panic("database: missing migration")
+	}
+}
+
+// VersionError is returned by Load if the database file
+// was written with a newer (unsupported) version of the schema.
+type VersionError struct {
+	Path    string
+	Version int
+}
+
+func (e VersionError) Error() string {
+	return common.Tr(errDbVersion, e.Path, e.Version, SchemaVersion)
+}
+
+// migrate upgrades the database to the current schema version.
+func (db *Database) migrate() error {
+	if db.Version > SchemaVersion {
+		return VersionError{Version: db.Version}
+	}
+	for db.Version < SchemaVersion {
+		if err := migrations[db.Version](db); err != nil {
+			return err
+		}
+		db.Version++
+	}
+	return nil
+}
+
+// BackupPath returns the path of the backup of the database file.
+func BackupPath(fpath string) string {
+	return fpath + ".bak"
+}
+
+// CorruptedPath returns the path where a corrupted database file is moved.
+func CorruptedPath(fpath string) string {
+	return fpath + ".corrupted"
+}
+
+// CorruptedError is returned by Load if the database file cannot be decoded.
+// If Recovered is true, the returned database comes from the backup.
+type CorruptedError struct {
+	Path      string
+	Err       error
+	Recovered bool
+}
+
+func (e CorruptedError) Error() string {
+	if e.Recovered {
+		return common.Tr(errDbRecovered, e.Path, e.Err.Error(), BackupPath(e.Path))
+	}
+	return common.Tr(errDbCorrupted, e.Path, e.Err.Error(), CorruptedPath(e.Path))
+}
+
+func (e CorruptedError) Unwrap() error {
+	return e.Err
+}
+
+// fileVersion returns the schema version of the content of a database file.
+func fileVersion(content []byte) (int, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	err := json.Unmarshal(content, &header)
+	return header.Version, err
+}
+
+func decodeFile(fpath string, ignored ...string) (db Database, err error) {
+	var file *os.File
+	if file, err = os.Open(fpath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	db = New(ignored...)
+	db.Version = 0
+	if err = db.Decode(file); err == nil {
+		err = db.migrate()
+	}
+	if e, ok := err.(VersionError); ok {
+		e.Path = fpath
+		err = e
+	}
+
+	return
+}
+
+// Load decodes the file in the given path and
+// returns the decoded database, migrated to the current schema.
+// If the file cannot be decoded, it is moved aside and the database
+// is restored from the backup if possible. In all cases,
+// a CorruptedError is returned.
+func Load(fpath string, ignored ...string) (db Database, err error) {
+	db, err = decodeFile(fpath, ignored...)
+	if _, ok := err.(VersionError); ok || err == nil || os.IsNotExist(err) {
+		return
+	}
+
+	os.Rename(fpath, CorruptedPath(fpath))
+	e := CorruptedError{Path: fpath, Err: err}
+	if backup, errBackup := decodeFile(BackupPath(fpath), ignored...); errBackup == nil {
+		db, e.Recovered = backup, true
+	} else {
+		db = New(ignored...)
+	}
+
+	return db, e
+}
+
+func copyFile(src, dest string) (err error) {
+	var in, out *os.File
+	if in, err = os.Open(src); err != nil {
+		return
+	}
+	defer in.Close()
+	if out, err = os.Create(dest); err != nil {
+		return
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	return
+}
+
+// backup keeps the current database file as a backup.
+func backup(fpath string) error {
+	bpath := BackupPath(fpath)
+	if err := os.Remove(bpath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(fpath, bpath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return copyFile(fpath, bpath)
+	}
+	return nil
+}
+
+// Save writes the database into the file on the given path.
+// The file is written atomically: the database is encoded
+// in a temporary file which then replaces the previous file.
+// The previous file is kept as a backup, unless the content
+// of the database did not change: in this case, nothing is written.
+// If the existing file was written with a newer version of the schema,
+// it is not overwritten and a VersionError is returned.
+func Save(fpath string, db Database) (err error) {
+	current, errRead := os.ReadFile(fpath)
+	if errRead == nil {
+		if version, e := fileVersion(current); e == nil && version > SchemaVersion {
+			return VersionError{Path: fpath, Version: version}
+		}
+	}
+
+	db.Version = SchemaVersion
+	var buf bytes.Buffer
+	if err = db.Encode(&buf); err != nil {
+		return
+	}
+
+	// Nothing changed: keep the file and its backup as is.
+	if errRead == nil && bytes.Equal(current, buf.Bytes()) {
+		return
+	}
+
+	dir, name := filepath.Dir(fpath), filepath.Base(fpath)
+	var file *os.File
+	if file, err = os.CreateTemp(dir, "."+name+".*.tmp"); err != nil {
+		return
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err = file.Write(buf.Bytes()); err == nil {
+		err = file.Sync()
+	}
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return
+	}
+	if err = os.Chmod(tmpPath, 0644); err != nil {
+		return
+	}
+
+	if err = backup(fpath); err != nil {
+		return
+	}
+	if err = os.Rename(tmpPath, fpath); err != nil {
+		return
+	}
+
+	// Sync the directory to make the rename persistent.
+	if d, e := os.Open(dir); e == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return
+}
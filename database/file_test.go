@@ -0,0 +1,134 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, fpath, content string) {
+	t.Helper()
+	if err := os.WriteFile(fpath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMigration(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"version 0", `{"packages":[{"name":"foo"},{"name":"bar","source":"other"}]}`},
+		{"version 1", `{"version":1,"packages":[{"name":"foo"},{"name":"bar","source":"other"}]}`},
+	}
+
+	for _, tt := range tests {
+		fpath := filepath.Join(t.TempDir(), "kcp.json")
+		writeFile(t, fpath, tt.content)
+		db, err := Load(fpath)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if db.Version != SchemaVersion {
+			t.Errorf("%s: version %d, want %d", tt.name, db.Version, SchemaVersion)
+		}
+		if foo, _ := db.Get("foo"); foo.Source != DefaultSource {
+			t.Errorf("%s: source of foo is %q", tt.name, foo.Source)
+		}
+		if bar, _ := db.Get("bar"); bar.Source != "other" {
+			t.Errorf("%s: source of bar is %q", tt.name, bar.Source)
+		}
+	}
+}
+
+func TestLoadCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	fpath := filepath.Join(dir, "kcp.json")
+
+	// Without backup, an empty database is returned.
+	writeFile(t, fpath, `{"packages":[`)
+	db, err := Load(fpath, "ignored")
+	var e CorruptedError
+	if !errors.As(err, &e) || e.Recovered {
+		t.Fatalf("Load without backup: %v", err)
+	}
+	if len(db.Packages) != 0 || len(db.IgnoreRepos) != 1 {
+		t.Errorf("Load without backup: %+v", db)
+	}
+	if _, err := os.Stat(CorruptedPath(fpath)); err != nil {
+		t.Errorf("corrupted file not moved: %v", err)
+	}
+	if _, err := os.Stat(fpath); !os.IsNotExist(err) {
+		t.Errorf("corrupted file still there: %v", err)
+	}
+
+	// With a backup, the database is restored from the backup.
+	writeFile(t, fpath, "garbage")
+	writeFile(t, BackupPath(fpath), `{"version":2,"packages":[{"name":"foo","source":"kcp"}]}`)
+	db, err = Load(fpath)
+	if !errors.As(err, &e) || !e.Recovered {
+		t.Fatalf("Load with backup: %v", err)
+	}
+	if !db.Contains("foo") {
+		t.Errorf("Load with backup: %+v", db)
+	}
+}
+
+func TestLoadNewerVersion(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "kcp.json")
+	writeFile(t, fpath, `{"version":99,"packages":[]}`)
+
+	_, err := Load(fpath)
+	var e VersionError
+	if !errors.As(err, &e) || e.Version != 99 || e.Path != fpath {
+		t.Fatalf("Load: %v", err)
+	}
+	// The file is kept as is.
+	if _, err := os.Stat(CorruptedPath(fpath)); !os.IsNotExist(err) {
+		t.Errorf("file moved: %v", err)
+	}
+
+	if err = Save(fpath, New()); !errors.As(err, &e) {
+		t.Errorf("Save: %v", err)
+	}
+	if b, _ := os.ReadFile(fpath); string(b) != `{"version":99,"packages":[]}` {
+		t.Errorf("file overwritten: %s", b)
+	}
+}
+
+func TestSave(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "kcp.json")
+	db := New()
+	db.Push(Package{Name: "foo"})
+
+	if err := Save(fpath, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(BackupPath(fpath)); !os.IsNotExist(err) {
+		t.Errorf("backup of a new file: %v", err)
+	}
+	saved, err := Load(fpath)
+	if err != nil || !saved.Contains("foo") || saved.Version != SchemaVersion {
+		t.Fatalf("Load: %+v, %v", saved, err)
+	}
+
+	// Saving the same content doesn't rotate the backup.
+	if err = Save(fpath, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(BackupPath(fpath)); !os.IsNotExist(err) {
+		t.Errorf("backup of an unchanged file: %v", err)
+	}
+
+	// The previous file is kept as backup.
+	db.Push(Package{Name: "bar"})
+	if err = Save(fpath, db); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := Load(BackupPath(fpath))
+	if err != nil || !backup.Contains("foo") || backup.Contains("bar") {
+		t.Errorf("backup: %+v, %v", backup, err)
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -49,7 +50,7 @@ type forgejoRepo struct {
 }
 
 // doRequest handles the common logic for making requests to the Forgejo API.
-func (fc *ForgejoConnector) doRequest(method string, query url.Values) (io.Reader, http.Header, error) {
+func (fc *ForgejoConnector) doRequest(ctx context.Context, method string, query url.Values) (io.Reader, http.Header, error) {
 	requestURL := fmt.Sprintf("%s/api/v1/orgs/%s/repos", fc.host, fc.organization)
 
 	header := http.Header{}
@@ -59,7 +60,7 @@ func (fc *ForgejoConnector) doRequest(method string, query url.Values) (io.Reade
 		header.Set("Authorization", "token "+fc.token)
 	}
 
-	return common.Request(requestURL, common.Context{
+	return common.Request(ctx, requestURL, common.Context{
 		Method: method,
 		Header: header,
 		Query:  query,
@@ -67,11 +68,11 @@ func (fc *ForgejoConnector) doRequest(method string, query url.Values) (io.Reade
 }
 
 // CountPublcRepos counts the number of public repositories in the organization.
-func (fc *ForgejoConnector) CountPublcRepos() (int, error) {
+func (fc *ForgejoConnector) CountPublcRepos(ctx context.Context) (int, error) {
 	query := url.Values{}
 	query.Set("limit", "1")
 
-	_, responseHeader, err := fc.doRequest(http.MethodGet, query)
+	_, responseHeader, err := fc.doRequest(ctx, http.MethodGet, query)
 	if err != nil {
 		return 0, err
 	}
@@ -90,12 +91,12 @@ func (fc *ForgejoConnector) CountPublcRepos() (int, error) {
 }
 
 // GetPage retrieves a paginated list of packages from the organization.
-func (fc *ForgejoConnector) GetPage(page, limit int) ([]Package, error) {
+func (fc *ForgejoConnector) GetPage(ctx context.Context, page, limit int) ([]Package, error) {
 	query := url.Values{}
 	query.Set("page", strconv.Itoa(page))
 	query.Set("limit", strconv.Itoa(limit))
 
-	responseBody, _, err := fc.doRequest(http.MethodGet, query)
+	responseBody, _, err := fc.doRequest(ctx, http.MethodGet, query)
 	if err != nil {
 		return nil, err
 	}
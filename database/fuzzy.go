@@ -0,0 +1,174 @@
+package database
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"codeberg.org/bvaudour/kcp/alpm"
+)
+
+// Scores of the fuzzy matching, from the most relevant to the less relevant.
+const (
+	scoreExactName        = 1000
+	scorePrefixName       = 900
+	scoreInName           = 700
+	scoreExactProvide     = 650
+	scoreTypoName         = 600
+	scoreInProvide        = 500
+	scoreSubsequenceName  = 300
+	scoreInDescription    = 200
+	scoreTypoDescription  = 100
+	scoreTypoDistanceCost = 100
+)
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(s1, s2 string) int {
+	r1, r2 := []rune(s1), []rune(s2)
+	prev, current := make([]int, len(r2)+1), make([]int, len(r2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		current[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			current[j] = min(prev[j]+1, current[j-1]+1, prev[j-1]+cost)
+		}
+		prev, current = current, prev
+	}
+	return prev[len(r2)]
+}
+
+// maxTypos returns the maximal edit distance allowed
+// to consider that a word is a typo of the search.
+func maxTypos(search string) int {
+	return min(3, utf8.RuneCountInString(search)/4+1)
+}
+
+// isSubsequence checks if all chars of search
+// appear in s in the same order.
+func isSubsequence(search, s string) bool {
+	for _, r := range search {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+func scoreWord(p Package, word string) (score int) {
+	name := strings.ToLower(p.Name)
+	switch {
+	case name == word:
+		return scoreExactName
+	case strings.HasPrefix(name, word):
+		score = max(score, scorePrefixName-utf8.RuneCountInString(name)+utf8.RuneCountInString(word))
+	case strings.Contains(name, word):
+		score = max(score, scoreInName-strings.Index(name, word))
+	}
+	for _, e := range p.Provides {
+		e = strings.ToLower(alpm.DependName(e))
+		if e == word {
+			score = max(score, scoreExactProvide)
+		} else if strings.Contains(e, word) {
+			score = max(score, scoreInProvide)
+		}
+	}
+	if d := levenshtein(name, word); d <= maxTypos(word) {
+		score = max(score, scoreTypoName-d*scoreTypoDistanceCost)
+	} else if isSubsequence(word, name) {
+		score = max(score, scoreSubsequenceName)
+	}
+	if score > 0 {
+		return
+	}
+
+	description := strings.ToLower(p.Description)
+	if strings.Contains(description, word) {
+		return scoreInDescription
+	}
+	for w := range strings.FieldsFuncSeq(description, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '+' || r == '.' || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9'))
+	}) {
+		if levenshtein(w, word) <= maxTypos(word) {
+			return scoreTypoDescription
+		}
+	}
+
+	return
+}
+
+// FuzzyScore returns the relevance of the package for the given search.
+// Exact and prefix matches of the name get the highest scores,
+// then matches on the provides and typos on the name, and finally
+// matches on the description. If one word of the search doesn’t
+// match at all, the score is 0.
+func FuzzyScore(p Package, search string) (score int) {
+	words := strings.Fields(strings.ToLower(search))
+	if len(words) > 1 && strings.EqualFold(p.Name, strings.Join(words, "-")) {
+		return scoreExactName * len(words)
+	}
+	for _, w := range words {
+		s := scoreWord(p, w)
+		if s == 0 {
+			return 0
+		}
+		score += s
+	}
+	return
+}
+
+func newFuzzyScorer(search string) func(Package) int {
+	scores := make(map[string]int)
+	return func(p Package) int {
+		if s, ok := scores[p.Name]; ok {
+			return s
+		}
+		s := FuzzyScore(p, search)
+		scores[p.Name] = s
+		return s
+	}
+}
+
+// NewFuzzyFilter returns a filter which keeps
+// the packages with a positive fuzzy score.
+func NewFuzzyFilter(search string) FilterFunc {
+	return func(p Package) bool {
+		return FuzzyScore(p, search) > 0
+	}
+}
+
+// SortByScore returns a sorter which sorts packages
+// according to their fuzzy score for the given search,
+// most relevant first.
+func SortByScore(search string) SorterFunc {
+	score := newFuzzyScorer(search)
+	return func(p1, p2 Package) int {
+		s1, s2 := score(p1), score(p2)
+		if s1 > s2 {
+			return -1
+		} else if s1 < s2 {
+			return 1
+		}
+		return 0
+	}
+}
+
+// Suggest returns at most limit names of packages which
+// are close to the given name, the closest first.
+func (pl Packages) Suggest(name string, limit int) []string {
+	score := newFuzzyScorer(name)
+	l := pl.Filter(func(p Package) bool { return score(p) >= scoreSubsequenceName })
+	l.Sort(func(p1, p2 Package) int { return score(p2) - score(p1) }, SortByName)
+	names := l.Names()
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	return slices.Clip(names)
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		s1, s2   string
+		distance int
+	}{
+		{"", "", 0},
+		{"kcp", "", 3},
+		{"", "kcp", 3},
+		{"kcp", "kcp", 0},
+		{"kitten", "sitting", 3},
+		{"firefox", "firefxo", 2},
+		{"éàü", "eau", 3},
+	}
+
+	for _, tt := range tests {
+		if d := levenshtein(tt.s1, tt.s2); d != tt.distance {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.s1, tt.s2, d, tt.distance)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	p := Package{
+		Name:        "visual-studio-code",
+		Description: "Editor for building and debugging web applications",
+		Provides:    []string{"code=1.80", "vscode"},
+	}
+
+	tests := []struct {
+		search string
+		score  int
+	}{
+		{"visual-studio-code", scoreExactName},
+		{"Visual Studio Code", 3 * scoreExactName},
+		{"visual", scorePrefixName - 12},
+		{"studio", scoreInName - 7},
+		{"vscode", scoreExactProvide},
+		{"vsc", scoreInProvide},
+		{"visual-studio-cdoe", scoreTypoName - 2*scoreTypoDistanceCost},
+		{"vsstdcode", scoreSubsequenceName},
+		{"debugging", scoreInDescription},
+		{"debuging", scoreTypoDescription},
+		{"visual debugging", scorePrefixName - 12 + scoreInDescription},
+		{"visual emacs", 0},
+		{"emacs", 0},
+	}
+
+	for _, tt := range tests {
+		if score := FuzzyScore(p, tt.search); score != tt.score {
+			t.Errorf("FuzzyScore(%q) = %d, want %d", tt.search, score, tt.score)
+		}
+	}
+}
+
+func TestSortByScore(t *testing.T) {
+	pl := Packages{
+		{Name: "kodi-addon", Description: "An addon for kodi"},
+		{Name: "kodi-git"},
+		{Name: "kodi"},
+		{Name: "xbmc", Provides: []string{"kodi"}},
+	}
+	pl = pl.Filter(NewFuzzyFilter("kodi"))
+	pl.Sort(SortByScore("kodi"), SortByName)
+	if names := pl.Names(); !slices.Equal(names, []string{"kodi", "kodi-git", "kodi-addon", "xbmc"}) {
+		t.Errorf("sorted by score: %v", names)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	pl := Packages{
+		{Name: "firefox"},
+		{Name: "firefox-nightly"},
+		{Name: "thunderbird"},
+		{Name: "fire", Description: "firefox"},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		names []string
+	}{
+		{"firefx", 5, []string{"firefox", "fire", "firefox-nightly"}},
+		{"firefx", 1, []string{"firefox"}},
+		{"thunderbrd", 5, []string{"thunderbird"}},
+		{"gimp", 5, []string{}},
+	}
+
+	for _, tt := range tests {
+		if names := pl.Suggest(tt.name, tt.limit); !slices.Equal(names, tt.names) {
+			t.Errorf("Suggest(%q, %d) = %v, want %v", tt.name, tt.limit, names, tt.names)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -39,13 +40,13 @@ func NewGithubConnector(org string, auth ...string) *GithubConnector {
 }
 
 // doRequest handles the common logic for making requests to the Github API.
-func (gc *GithubConnector) doRequest(method, path string, query url.Values) (io.Reader, http.Header, error) {
+func (gc *GithubConnector) doRequest(ctx context.Context, method, path string, query url.Values) (io.Reader, http.Header, error) {
 	requestURL := fmt.Sprintf("https://api.github.com%s", path)
 
 	header := http.Header{}
 	header.Set("Accept", "application/vnd.github.v3+json")
 
-	ctx := common.Context{
+	options := common.Context{
 		Method: method,
 		Header: header,
 		Query:  query,
@@ -54,16 +55,16 @@ func (gc *GithubConnector) doRequest(method, path string, query url.Values) (io.
 	if gc.token != "" {
 		header.Set("Authorization", "token "+gc.token)
 	} else if gc.auth != nil {
-		ctx.BasicAuth = gc.auth
+		options.BasicAuth = gc.auth
 	}
 
-	return common.Request(requestURL, ctx)
+	return common.Request(ctx, requestURL, options)
 }
 
 // CountPublcRepos counts the number of public repositories in the organization.
-func (gc *GithubConnector) CountPublcRepos() (int, error) {
+func (gc *GithubConnector) CountPublcRepos(ctx context.Context) (int, error) {
 	path := fmt.Sprintf("/orgs/%s", gc.organization)
-	responseBody, _, err := gc.doRequest(http.MethodGet, path, nil)
+	responseBody, _, err := gc.doRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return 0, err
 	}
@@ -82,14 +83,14 @@ func (gc *GithubConnector) CountPublcRepos() (int, error) {
 }
 
 // GetPage retrieves a paginated list of packages from the organization.
-func (gc *GithubConnector) GetPage(page, limit int) ([]Package, error) {
+func (gc *GithubConnector) GetPage(ctx context.Context, page, limit int) ([]Package, error) {
 	path := fmt.Sprintf("/orgs/%s/repos", gc.organization)
 	query := url.Values{}
 	query.Set("page", strconv.Itoa(page))
 	query.Set("per_page", strconv.Itoa(limit))
 	query.Set("sort", "pushed")
 
-	responseBody, _, err := gc.doRequest(http.MethodGet, path, query)
+	responseBody, _, err := gc.doRequest(ctx, http.MethodGet, path, query)
 	if err != nil {
 		return nil, err
 	}
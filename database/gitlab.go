@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/bvaudour/kcp/common"
+)
+
+// GitlabConnector implements the Connector interface for a Gitlab instance.
+// The organization is the full path of a group (like group/subgroup).
+// The projects of its subgroups are included.
+type GitlabConnector struct {
+	host  string
+	group string
+	token string
+}
+
+// NewGitlabConnector creates a new GitlabConnector.
+// The auth parameter is optional and can be a token (1 value)
+// or a username and password (2 values). Since the Gitlab API
+// doesn't support basic authentication, the password
+// must be a personal access token.
+// If the domain doesn't contain a scheme, https is used.
+func NewGitlabConnector(domain, group string, auth ...string) *GitlabConnector {
+	gc := &GitlabConnector{
+		host:  domain,
+		group: group,
+	}
+	if !strings.Contains(domain, "://") {
+		gc.host = "https://" + domain
+	}
+	gc.host = strings.TrimSuffix(gc.host, "/")
+	if len(auth) == 1 {
+		gc.token = auth[0]
+	} else if len(auth) == 2 {
+		gc.token = auth[1]
+	}
+	return gc
+}
+
+// gitlabProject represents a project as returned by the Gitlab API.
+type gitlabProject struct {
+	ID             int       `json:"id"`
+	Path           string    `json:"path"`
+	Description    string    `json:"description"`
+	CloneURL       string    `json:"http_url_to_repo"`
+	WebURL         string    `json:"web_url"`
+	SSHURL         string    `json:"ssh_url_to_repo"`
+	Stars          int       `json:"star_count"`
+	Branch         string    `json:"default_branch"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// header returns the headers common to all requests.
+func (gc *GitlabConnector) header() http.Header {
+	header := http.Header{}
+	if gc.token != "" {
+		header.Set("PRIVATE-TOKEN", gc.token)
+	}
+	return header
+}
+
+// doRequest handles the common logic for making requests to the Gitlab API.
+func (gc *GitlabConnector) doRequest(ctx context.Context, method string, query url.Values) (io.Reader, http.Header, error) {
+	requestURL := fmt.Sprintf("%s/api/v4/groups/%s/projects", gc.host, url.PathEscape(gc.group))
+
+	header := gc.header()
+	header.Set("Accept", "application/json")
+
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("archived", "false")
+	query.Set("include_subgroups", "true")
+	query.Set("order_by", "id")
+	query.Set("sort", "asc")
+
+	return common.Request(ctx, requestURL, common.Context{
+		Method: method,
+		Header: header,
+		Query:  query,
+	})
+}
+
+// CountPublcRepos counts the number of projects in the group.
+func (gc *GitlabConnector) CountPublcRepos(ctx context.Context) (int, error) {
+	query := url.Values{}
+	query.Set("per_page", "1")
+
+	_, responseHeader, err := gc.doRequest(ctx, http.MethodGet, query)
+	if err != nil {
+		return 0, err
+	}
+
+	totalCountStr := responseHeader.Get("X-Total")
+	if totalCountStr == "" {
+		return 0, fmt.Errorf(errCountHeaderNotFound)
+	}
+
+	totalCount, err := strconv.Atoi(totalCountStr)
+	if err != nil {
+		return 0, fmt.Errorf(errCountHeader, err)
+	}
+
+	return totalCount, nil
+}
+
+// pkgbuildURL returns the URL of the raw PKGBUILD of the project.
+// The API is used instead of the raw route of the web interface,
+// since the latter ignores the token of private projects.
+func (gc *GitlabConnector) pkgbuildURL(project gitlabProject) string {
+	return fmt.Sprintf(
+		"%s/api/v4/projects/%d/repository/files/PKGBUILD/raw?ref=%s",
+		gc.host,
+		project.ID,
+		url.QueryEscape(project.Branch),
+	)
+}
+
+// GetPage retrieves a paginated list of packages from the group.
+func (gc *GitlabConnector) GetPage(ctx context.Context, page, limit int) ([]Package, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(limit))
+
+	responseBody, _, err := gc.doRequest(ctx, http.MethodGet, query)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := io.ReadAll(responseBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []gitlabProject
+	if err := json.Unmarshal(bodyBytes, &projects); err != nil {
+		return nil, err
+	}
+
+	packages := make([]Package, len(projects))
+	for i, project := range projects {
+		packages[i] = Package{
+			Name:        project.Path,
+			Description: project.Description,
+			CreatedAt:   project.CreatedAt,
+			UpdatedAt:   project.LastActivityAt,
+			PushedAt:    project.LastActivityAt, // PushedAt is not available in Gitlab, using LastActivityAt
+			RepoUrl:     project.WebURL,
+			CloneUrl:    project.CloneURL,
+			SshUrl:      project.SSHURL,
+			PkgbuildUrl: gc.pkgbuildURL(project),
+			Stars:       project.Stars,
+			Branch:      project.Branch,
+			RepoVersion: "", // Version is not available from this endpoint.
+		}
+	}
+
+	return packages, nil
+}
+
+// ReadPKGBUILD reads the PKGBUILD of the package, authenticated
+// with the token of the connector if any.
+func (gc *GitlabConnector) ReadPKGBUILD(ctx context.Context, p Package) (io.Reader, error) {
+	body, _, err := common.Request(ctx, p.PkgbuildUrl, common.Context{
+		Header: gc.header(),
+	})
+	return body, err
+}
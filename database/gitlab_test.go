@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGitlabConnector(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	const token = "secret"
+	created := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	updated := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/groups/kaos/projects", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Header.Get("PRIVATE-TOKEN") != token || query.Get("archived") != "false" || query.Get("order_by") != "id" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		projects := []gitlabProject{{
+			ID:             7,
+			Path:           "foo",
+			Description:    "A foo package",
+			CloneURL:       "https://gitlab.example.org/kaos/foo.git",
+			WebURL:         "https://gitlab.example.org/kaos/foo",
+			Stars:          5,
+			Branch:         "main",
+			CreatedAt:      created,
+			LastActivityAt: updated,
+		}}
+		// The projects of the subgroups are only listed on demand.
+		if query.Get("include_subgroups") == "true" {
+			projects = append(projects, gitlabProject{ID: 8, Path: "bar", Branch: "main"})
+		}
+		w.Header().Set("X-Total", strconv.Itoa(len(projects)))
+		json.NewEncoder(w).Encode(projects)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	gc := NewGitlabConnector(server.URL, "kaos", token)
+	count, err := gc.CountPublcRepos(context.Background())
+	if err != nil || count != 2 {
+		t.Fatalf("CountPublcRepos() = %d, %v", count, err)
+	}
+
+	packages, err := gc.GetPage(context.Background(), 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 2 || packages[1].Name != "bar" {
+		t.Fatalf("unexpected packages: %v", packages)
+	}
+	p := packages[0]
+	if p.Name != "foo" || p.Description != "A foo package" || p.Stars != 5 || p.Branch != "main" {
+		t.Errorf("bad package: %+v", p)
+	}
+	if !p.CreatedAt.Equal(created) || !p.UpdatedAt.Equal(updated) {
+		t.Errorf("bad dates: %v, %v", p.CreatedAt, p.UpdatedAt)
+	}
+	if want := server.URL + "/api/v4/projects/7/repository/files/PKGBUILD/raw?ref=main"; p.PkgbuildUrl != want {
+		t.Errorf("PkgbuildUrl = %q, want %q", p.PkgbuildUrl, want)
+	}
+
+	// The basic authentication uses the password as token.
+	if _, err = NewGitlabConnector(server.URL, "kaos", "user", token).GetPage(context.Background(), 1, 10); err != nil {
+		t.Errorf("GetPage with user and password: %v", err)
+	}
+	if _, err = NewGitlabConnector(server.URL, "kaos").GetPage(context.Background(), 1, 10); err == nil {
+		t.Error("GetPage without token succeeded")
+	}
+}
+
+func TestGitlabPKGBUILD(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	const token = "secret"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/groups/kaos/projects", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]gitlabProject{{ID: 42, Path: "foo", Branch: "main"}})
+	})
+	mux.HandleFunc("/api/v4/projects/42/repository/files/PKGBUILD/raw", func(w http.ResponseWriter, r *http.Request) {
+		// Like Gitlab, a private project is hidden without the token.
+		if r.Header.Get("PRIVATE-TOKEN") != token || r.URL.Query().Get("ref") != "main" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "pkgname=foo\npkgver=1.2\npkgrel=3\n")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	gc := NewGitlabConnector(server.URL, "kaos", token)
+	packages, err := gc.GetPage(context.Background(), 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 {
+		t.Fatalf("unexpected packages: %v", packages)
+	}
+	p := packages[0]
+	if want := server.URL + "/api/v4/projects/42/repository/files/PKGBUILD/raw?ref=main"; p.PkgbuildUrl != want {
+		t.Errorf("PkgbuildUrl = %q, want %q", p.PkgbuildUrl, want)
+	}
+
+	file, err := getPKGBUILD(context.Background(), gc, p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := file.GetFullVersion(); v != "1.2-3" {
+		t.Errorf("GetFullVersion() = %q", v)
+	}
+
+	// Without the token, the PKGBUILD is not readable.
+	anonymous := NewGitlabConnector(server.URL, "kaos")
+	if _, err = getPKGBUILD(context.Background(), anonymous, p, false); err == nil {
+		t.Error("PKGBUILD read without token")
+	}
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"codeberg.org/bvaudour/kcp/common"
+	"codeberg.org/bvaudour/kcp/pkgbuild"
+)
+
+// Hold is a rule which holds back the upgrades of the packages
+// whose name matches the pattern (a glob, like pacman’s IgnorePkg).
+// A hold can also pin the package to a git commit (name@commit)
+// or to a version (name=version), used when the package is installed.
+type Hold struct {
+	Pattern string
+	Commit  string
+	Version string
+}
+
+// ParseHold parses a hold entry: pattern, name@commit or name=version.
+func ParseHold(entry string) Hold {
+	if name, commit, ok := strings.Cut(entry, "@"); ok {
+		return Hold{Pattern: name, Commit: commit}
+	}
+	if name, version, ok := strings.Cut(entry, "="); ok {
+		return Hold{Pattern: name, Version: version}
+	}
+	return Hold{Pattern: entry}
+}
+
+// IsPin returns true if the hold pins the package to a commit or a version.
+func (h Hold) IsPin() bool {
+	return h.Commit != "" || h.Version != ""
+}
+
+// Match checks if the name of a package matches the pattern of the hold.
+func (h Hold) Match(name string) bool {
+	ok, err := path.Match(h.Pattern, name)
+	return ok || (err != nil && h.Pattern == name)
+}
+
+func (h Hold) String() string {
+	switch {
+	case h.Commit != "":
+		return h.Pattern + "@" + h.Commit
+	case h.Version != "":
+		return h.Pattern + "=" + h.Version
+	}
+	return h.Pattern
+}
+
+// Checkout checks out the pinned commit or version
+// in the given clone of the package.
+// If the hold is not a pin, it does nothing.
+func (h Hold) Checkout(ctx context.Context, repo string) (err error) {
+	commit := h.Commit
+	if h.Version != "" {
+		if commit, err = findVersion(ctx, repo, h.Version); err != nil {
+			return
+		}
+	}
+	if commit == "" {
+		return
+	}
+	if _, err = git(ctx, repo, "checkout", "--quiet", commit); err != nil {
+		err = errors.New(common.Tr(errPinCheckout, h.String(), err.Error()))
+	}
+	return
+}
+
+// findVersion returns the last commit of the repository
+// whose PKGBUILD gives the searched version.
+func findVersion(ctx context.Context, repo, version string) (string, error) {
+	commits, err := git(ctx, repo, "log", "--format=%H", "HEAD", "--", "PKGBUILD")
+	if err != nil {
+		return "", err
+	}
+	for commit := range strings.FieldsSeq(commits) {
+		body, err := git(ctx, repo, "show", commit+":PKGBUILD")
+		if err != nil {
+			continue
+		}
+		if file, err := pkgbuild.DecodeVars(strings.NewReader(body)); err == nil && file.GetFullVersion() == version {
+			return commit, nil
+		}
+	}
+	return "", errors.New(common.Tr(errPinVersion, version))
+}
+
+// Holds is a list of holds.
+type Holds []Hold
+
+// ParseHolds parses the given hold entries.
+func ParseHolds(entries ...string) Holds {
+	holds := make(Holds, 0, len(entries))
+	for _, e := range entries {
+		if e != "" {
+			holds = append(holds, ParseHold(e))
+		}
+	}
+	return holds
+}
+
+// Get returns the hold matching the name of the package.
+// If several holds match, a pin is preferred.
+func (hl Holds) Get(name string) (hold Hold, ok bool) {
+	for _, h := range hl {
+		if !h.Match(name) {
+			continue
+		}
+		if !ok || (h.IsPin() && !hold.IsPin()) {
+			hold, ok = h, true
+		}
+	}
+	return
+}
+
+// Hold marks the packages of the database held by the given holds.
+func (db *Database) Hold(holds Holds) {
+	for i, p := range db.Packages {
+		db.Packages[i].Hold = nil
+		if h, ok := holds.Get(p.Name); ok {
+			db.Packages[i].Hold = &h
+		}
+	}
+}
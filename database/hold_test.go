@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseHold(t *testing.T) {
+	tests := []struct {
+		entry string
+		hold  Hold
+		pin   bool
+	}{
+		{"qt6-*", Hold{Pattern: "qt6-*"}, false},
+		{"foo@abc123", Hold{Pattern: "foo", Commit: "abc123"}, true},
+		{"foo=1.2-3", Hold{Pattern: "foo", Version: "1.2-3"}, true},
+		{"foo=1:1.2-3", Hold{Pattern: "foo", Version: "1:1.2-3"}, true},
+	}
+
+	for _, tt := range tests {
+		h := ParseHold(tt.entry)
+		if h != tt.hold || h.IsPin() != tt.pin || h.String() != tt.entry {
+			t.Errorf("ParseHold(%q) = %+v (pin: %v, string: %q)", tt.entry, h, h.IsPin(), h.String())
+		}
+	}
+}
+
+func TestHoldsGet(t *testing.T) {
+	holds := ParseHolds("", "qt6-*", "qt6-base@abc123", "[invalid", "kf6-kio")
+
+	tests := []struct {
+		name string
+		hold string
+	}{
+		{"qt6-base", "qt6-base@abc123"},
+		{"qt6-tools", "qt6-*"},
+		{"kf6-kio", "kf6-kio"},
+		{"[invalid", "[invalid"},
+		{"qt5-base", ""},
+	}
+
+	for _, tt := range tests {
+		h, ok := holds.Get(tt.name)
+		if ok != (tt.hold != "") || (ok && h.String() != tt.hold) {
+			t.Errorf("Get(%q) = %v, %v, want %q", tt.name, h, ok, tt.hold)
+		}
+	}
+
+	db := New()
+	db.Push(Package{Name: "qt6-base"}, Package{Name: "yay"})
+	db.Hold(holds)
+	if p, _ := db.Get("qt6-base"); p.Hold == nil || p.Hold.Commit != "abc123" {
+		t.Errorf("qt6-base not held: %+v", p.Hold)
+	}
+	if p, _ := db.Get("yay"); p.Hold != nil {
+		t.Errorf("yay held: %+v", p.Hold)
+	}
+}
+
+func TestHoldCheckout(t *testing.T) {
+	repo := newRepo(t, t.TempDir(), "kcptest-pin", date2020, "1.0", "1.1", "2.0")
+	head := func() string {
+		out, err := exec.Command("git", "-C", repo, "show", "--no-patch", "--format=%H %s", "HEAD").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	out, err := exec.Command("git", "-C", repo, "rev-list", "--max-parents=0", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := strings.TrimSpace(string(out))
+
+	ctx := context.Background()
+	if err := ParseHold("kcptest-pin=1.1-1").Checkout(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, subject, _ := strings.Cut(head(), " "); subject != "Version 1.1" {
+		t.Errorf("pinned version: HEAD is %q", subject)
+	}
+
+	runGit(t, repo, date2020, "checkout", "--quiet", "main")
+	if err := ParseHold("kcptest-pin@"+first[:7]).Checkout(ctx, repo); err != nil {
+		t.Fatal(err)
+	}
+	if commit, _, _ := strings.Cut(head(), " "); commit != first {
+		t.Errorf("pinned commit: HEAD is %q, want %q", commit, first)
+	}
+
+	if err := ParseHold("kcptest-pin=3.0-1").Checkout(ctx, repo); err == nil {
+		t.Error("unknown version checked out")
+	}
+	if err := ParseHold("kcptest-pin@0000000").Checkout(ctx, repo); err == nil {
+		t.Error("unknown commit checked out")
+	}
+	if err := ParseHold("kcptest-pin").Checkout(ctx, repo); err != nil {
+		t.Errorf("checkout without pin: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"codeberg.org/bvaudour/kcp/pkgbuild"
+	"codeberg.org/bvaudour/kcp/pkgbuild/standard"
+)
+
+// LocalConnector implements the Connector interface for a local directory
+// containing git repositories (cloned or bare) instead of a git server.
+// It allows to build the database without network access.
+type LocalConnector struct {
+	dir   string
+	mu    sync.Mutex
+	repos []string
+}
+
+// NewLocalConnector creates a new LocalConnector on the given directory.
+func NewLocalConnector(dir string) *LocalConnector {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return &LocalConnector{dir: dir}
+}
+
+// git launches a git command on the given repository and returns its trimmed output.
+func git(ctx context.Context, repo string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", repo}, args...)...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// listRepos returns the sorted paths of the git repositories
+// of the directory which have at least one commit.
+// The list is computed once.
+func (lc *LocalConnector) listRepos(ctx context.Context) ([]string, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.repos != nil {
+		return lc.repos, nil
+	}
+
+	entries, err := os.ReadDir(lc.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := []string{}
+	for _, e := range entries {
+		repo := filepath.Join(lc.dir, e.Name())
+		if fi, err := os.Stat(repo); err != nil || !fi.IsDir() {
+			continue
+		}
+		if _, err := git(ctx, repo, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+			repos = append(repos, repo)
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	slices.Sort(repos)
+	lc.repos = repos
+
+	return repos, nil
+}
+
+// readPKGBUILD returns the content of the PKGBUILD
+// of the last commit of the repository.
+func readPKGBUILD(ctx context.Context, repo string) (io.Reader, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", repo, "show", "HEAD:PKGBUILD").Output()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(out), nil
+}
+
+func (lc *LocalConnector) getPackage(ctx context.Context, repo string) Package {
+	p := Package{
+		Name:     strings.TrimSuffix(filepath.Base(repo), ".git"),
+		RepoUrl:  repo,
+		CloneUrl: repo,
+		SshUrl:   repo,
+	}
+
+	p.Branch, _ = git(ctx, repo, "symbolic-ref", "--short", "HEAD")
+	if date, err := git(ctx, repo, "log", "-1", "--format=%cI", "HEAD"); err == nil {
+		p.UpdatedAt, _ = time.Parse(time.RFC3339, date)
+		p.PushedAt = p.UpdatedAt
+	}
+
+	// The oldest root commit gives the creation date.
+	// Since the history can have several roots (merged repositories),
+	// the oldest date is searched among them.
+	if dates, err := git(ctx, repo, "log", "--max-parents=0", "--format=%cI", "HEAD"); err == nil {
+		for _, line := range strings.Split(dates, "\n") {
+			date, err := time.Parse(time.RFC3339, line)
+			if err == nil && (p.CreatedAt.IsZero() || date.Before(p.CreatedAt)) {
+				p.CreatedAt = date
+			}
+		}
+	}
+
+	// There is no repository description, so use the pkgdesc.
+	if body, err := readPKGBUILD(ctx, repo); err == nil {
+		if file, err := pkgbuild.DecodeVars(body); err == nil {
+			p.Description = file.GetValue(standard.PKGDESC)
+		}
+	}
+
+	return p
+}
+
+// CountPublcRepos counts the number of git repositories in the directory.
+func (lc *LocalConnector) CountPublcRepos(ctx context.Context) (int, error) {
+	repos, err := lc.listRepos(ctx)
+	return len(repos), err
+}
+
+// GetPage retrieves a paginated list of packages from the directory.
+func (lc *LocalConnector) GetPage(ctx context.Context, page, limit int) ([]Package, error) {
+	repos, err := lc.listRepos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	begin := min((page-1)*limit, len(repos))
+	end := min(begin+limit, len(repos))
+	packages := make([]Package, 0, end-begin)
+	for _, repo := range repos[begin:end] {
+		packages = append(packages, lc.getPackage(ctx, repo))
+	}
+
+	return packages, nil
+}
+
+// ReadPKGBUILD implements the PKGBUILDReader interface.
+func (lc *LocalConnector) ReadPKGBUILD(ctx context.Context, p Package) (io.Reader, error) {
+	return readPKGBUILD(ctx, p.CloneUrl)
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// runGit launches a git command in the given dir,
+// with the given date for the commits.
+func runGit(t *testing.T, dir string, date time.Time, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "init.defaultBranch=main", "-c", "commit.gpgSign=false"}, args...)...)
+	d := date.Format(time.RFC3339)
+	cmd.Env = append(
+		os.Environ(),
+		"GIT_AUTHOR_NAME=kcp", "GIT_AUTHOR_EMAIL=kcp@localhost", "GIT_AUTHOR_DATE="+d,
+		"GIT_COMMITTER_NAME=kcp", "GIT_COMMITTER_EMAIL=kcp@localhost", "GIT_COMMITTER_DATE="+d,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// commitPKGBUILD commits a PKGBUILD with the given version in the repo.
+func commitPKGBUILD(t *testing.T, repo, version string, date time.Time) {
+	t.Helper()
+	name := filepath.Base(repo)
+	content := fmt.Sprintf("pkgname=%s\npkgver=%s\npkgrel=1\npkgdesc='Package %s'\narch=('x86_64')\n", name, version, name)
+	if err := os.WriteFile(filepath.Join(repo, "PKGBUILD"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, date, "add", "PKGBUILD")
+	runGit(t, repo, date, "commit", "--quiet", "-m", "Version "+version)
+}
+
+// newRepo creates a git repository in the dir with one commit by version.
+func newRepo(t *testing.T, dir, name string, date time.Time, versions ...string) string {
+	t.Helper()
+	repo := filepath.Join(dir, name)
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, date, "init", "--quiet")
+	for i, v := range versions {
+		commitPKGBUILD(t, repo, v, date.Add(time.Duration(i)*time.Hour))
+	}
+	return repo
+}
+
+var (
+	date2019 = time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	date2020 = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	date2021 = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func TestLocalConnector(t *testing.T) {
+	dir, tmp := t.TempDir(), t.TempDir()
+
+	// A cloned repo with 2 commits.
+	newRepo(t, dir, "kcptest-normal", date2020, "1.0", "1.1")
+
+	// A bare repo.
+	src := newRepo(t, tmp, "kcptest-bare", date2020, "2.0")
+	runGit(t, tmp, date2020, "clone", "--quiet", "--bare", src, filepath.Join(dir, "kcptest-bare.git"))
+
+	// A repo with 2 root commits: the oldest one is not the first parent.
+	roots := newRepo(t, dir, "kcptest-roots", date2020, "3.0")
+	runGit(t, roots, date2019, "checkout", "--quiet", "--orphan", "old")
+	commitPKGBUILD(t, roots, "0.1", date2019)
+	runGit(t, roots, date2021, "checkout", "--quiet", "main")
+	runGit(t, roots, date2021, "merge", "--quiet", "--allow-unrelated-histories", "-s", "ours", "-m", "Merge", "old")
+
+	// Ignored entries: a file and an empty repo.
+	if err := os.WriteFile(filepath.Join(dir, "README"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, date2020, "init", "--quiet", "kcptest-empty")
+
+	db := New()
+	counter, err := db.UpdateRemote(context.Background(), NewLocalConnector(dir), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.Added != 3 || counter.Updated != 0 || counter.Deleted != 0 {
+		t.Errorf("counter: %+v", counter)
+	}
+	db.Sort(SortByName)
+	if names := db.Names(); !slices.Equal(names, []string{"kcptest-bare", "kcptest-normal", "kcptest-roots"}) {
+		t.Fatalf("names: %v", names)
+	}
+
+	tests := []struct {
+		name, repo, version string
+		created, updated    time.Time
+	}{
+		{"kcptest-bare", "kcptest-bare.git", "2.0-1", date2020, date2020},
+		{"kcptest-normal", "kcptest-normal", "1.1-1", date2020, date2020.Add(time.Hour)},
+		{"kcptest-roots", "kcptest-roots", "3.0-1", date2019, date2021},
+	}
+	for _, tt := range tests {
+		p, _ := db.Get(tt.name)
+		if p.RepoVersion != tt.version {
+			t.Errorf("%s: version %q, want %q", tt.name, p.RepoVersion, tt.version)
+		}
+		if repo := filepath.Join(dir, tt.repo); p.CloneUrl != repo || p.RepoUrl != repo {
+			t.Errorf("%s: urls %q, %q, want %q", tt.name, p.CloneUrl, p.RepoUrl, repo)
+		}
+		if p.Description != "Package "+tt.name || p.Branch != "main" {
+			t.Errorf("%s: description %q, branch %q", tt.name, p.Description, p.Branch)
+		}
+		if !p.CreatedAt.Equal(tt.created) || !p.UpdatedAt.Equal(tt.updated) {
+			t.Errorf("%s: created at %v, updated at %v, want %v, %v", tt.name, p.CreatedAt, p.UpdatedAt, tt.created, tt.updated)
+		}
+	}
+
+	// A new commit is detected by the next update.
+	commitPKGBUILD(t, filepath.Join(dir, "kcptest-normal"), "1.2", time.Now().Add(time.Hour))
+	if counter, err = db.UpdateRemote(context.Background(), NewLocalConnector(dir), false); err != nil {
+		t.Fatal(err)
+	}
+	if counter.Added != 0 || counter.Updated != 1 || counter.Deleted != 0 {
+		t.Errorf("counter of the second update: %+v", counter)
+	}
+	if p, _ := db.Get("kcptest-normal"); p.RepoVersion != "1.2-1" {
+		t.Errorf("version after update: %q", p.RepoVersion)
+	}
+}
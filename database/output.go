@@ -0,0 +1,156 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatTSV  = "tsv"
+)
+
+// OutputFormats returns the list of the supported output formats.
+func OutputFormats() []string {
+	return []string{FormatText, FormatJSON, FormatTSV}
+}
+
+// PackageOutput is the machine-readable representation of a package.
+// It contains all fields of the package, as stored in the database,
+// and the computed fields depending on the local system.
+type PackageOutput struct {
+	Package
+	Installed bool   `json:"installed"`
+	Outdated  bool   `json:"outdated"`
+	Newer     bool   `json:"newer"`
+	Held      bool   `json:"held"`
+	Pin       string `json:"pin"`
+}
+
+// Output returns the machine-readable representation of the package.
+func (p Package) Output() PackageOutput {
+	var pin string
+	if p.Hold != nil && p.Hold.IsPin() {
+		pin = p.Hold.String()
+	}
+	return PackageOutput{
+		Package:   p,
+		Installed: FilterInstalled(p),
+		Outdated:  FilterOutdated(p),
+		Newer:     FilterNewer(p),
+		Held:      FilterHeld(p),
+		Pin:       pin,
+	}
+}
+
+// Output returns the machine-readable representation of the list.
+func (pl Packages) Output() []PackageOutput {
+	out := make([]PackageOutput, len(pl))
+	for i, p := range pl {
+		out[i] = p.Output()
+	}
+	return out
+}
+
+var tsvColumns = []struct {
+	name  string
+	value func(PackageOutput) string
+}{
+	{"name", func(p PackageOutput) string { return p.Name }},
+	{"source", func(p PackageOutput) string { return p.SourceName() }},
+	{"remote_version", func(p PackageOutput) string { return p.RepoVersion }},
+	{"local_version", func(p PackageOutput) string { return p.LocalVersion }},
+	{"installed", func(p PackageOutput) string { return strconv.FormatBool(p.Installed) }},
+	{"outdated", func(p PackageOutput) string { return strconv.FormatBool(p.Outdated) }},
+	{"newer", func(p PackageOutput) string { return strconv.FormatBool(p.Newer) }},
+	{"held", func(p PackageOutput) string { return strconv.FormatBool(p.Held) }},
+	{"pin", func(p PackageOutput) string { return p.Pin }},
+	{"stargazers_count", func(p PackageOutput) string { return strconv.Itoa(p.Stars) }},
+	{"description", func(p PackageOutput) string { return p.Description }},
+	{"created_at", func(p PackageOutput) string { return tsvTime(p.CreatedAt) }},
+	{"updated_at", func(p PackageOutput) string { return tsvTime(p.UpdatedAt) }},
+	{"pushed_at", func(p PackageOutput) string { return tsvTime(p.PushedAt) }},
+	{"html_url", func(p PackageOutput) string { return p.RepoUrl }},
+	{"clone_url", func(p PackageOutput) string { return p.CloneUrl }},
+	{"ssh_url", func(p PackageOutput) string { return p.SshUrl }},
+	{"pkgbuild_url", func(p PackageOutput) string { return p.PkgbuildUrl }},
+	{"default_branch", func(p PackageOutput) string { return p.Branch }},
+	{"architectures", func(p PackageOutput) string { return tsvList(p.Arch) }},
+	{"upstream_url", func(p PackageOutput) string { return p.Url }},
+	{"licenses", func(p PackageOutput) string { return tsvList(p.Licenses) }},
+	{"provides", func(p PackageOutput) string { return tsvList(p.Provides) }},
+	{"depends", func(p PackageOutput) string { return tsvList(p.Depends) }},
+	{"make_depends", func(p PackageOutput) string { return tsvList(p.MakeDepends) }},
+	{"opt_depends", func(p PackageOutput) string { return tsvList(p.OptDepends) }},
+	{"conflicts", func(p PackageOutput) string { return tsvList(p.Conflicts) }},
+	{"replaces", func(p PackageOutput) string { return tsvList(p.Replaces) }},
+	{"validated_by", func(p PackageOutput) string { return p.ValidatedBy }},
+	{"has_install_script", func(p PackageOutput) string { return strconv.FormatBool(p.HasInstallScript) }},
+}
+
+var tsvReplacer = strings.NewReplacer(
+	"\\", "\\\\",
+	"\t", "\\t",
+	"\n", "\\n",
+	"\r", "\\r",
+)
+
+func tsvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func tsvList(l []string) string {
+	return strings.Join(l, ",")
+}
+
+// EncodeJSON writes the list of packages as a json array.
+func (pl Packages) EncodeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pl.Output())
+}
+
+// EncodeTSV writes the list of packages as tab-separated values.
+// The first line contains the names of the columns.
+// Lists are comma-separated, and tabulations, newlines and
+// backslashes are escaped.
+func (pl Packages) EncodeTSV(w io.Writer) error {
+	row := make([]string, len(tsvColumns))
+	for i, c := range tsvColumns {
+		row[i] = c.name
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+		return err
+	}
+	for _, p := range pl.Output() {
+		for i, c := range tsvColumns {
+			row[i] = tsvReplacer.Replace(c.value(p))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeJSON writes the package as a json object.
+func (p Package) EncodeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p.Output())
+}
+
+// EncodeTSV writes the package as tab-separated values,
+// with the columns’ header.
+func (p Package) EncodeTSV(w io.Writer) error {
+	return Packages{p}.EncodeTSV(w)
+}
@@ -1,22 +1,21 @@
 package database
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"path"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
 
+	"codeberg.org/bvaudour/kcp/alpm"
 	"codeberg.org/bvaudour/kcp/common"
 	"codeberg.org/bvaudour/kcp/pkgbuild"
 	"codeberg.org/bvaudour/kcp/pkgbuild/standard"
 	"git.kaosx.ovh/benjamin/collection"
-	"git.kaosx.ovh/benjamin/collection/concurrent"
 	"git.kaosx.ovh/benjamin/format"
 )
 
@@ -46,6 +45,8 @@ type Package struct {
 	Licenses         []string  `json:"licenses"`
 	ValidatedBy      string    `json:"validated_by"`
 	HasInstallScript bool      `json:"has_install_script"`
+	Source           string    `json:"source"`
+	Hold             *Hold     `json:"-"`
 	noChange         bool
 }
 
@@ -58,11 +59,11 @@ func (p Package) GetLocaleVersion() string {
 
 // GetPKGBUILD reads and parses the remote PKGBUILD
 // from the github organization URL.
-func (p Package) GetPKGBUID(debug ...bool) (file *pkgbuild.PKGBUILD, err error) {
+func (p Package) GetPKGBUID(ctx context.Context, debug ...bool) (file *pkgbuild.PKGBUILD, err error) {
 	url, printDebug := p.PkgbuildUrl, len(debug) > 0 && debug[0]
 
 	var body io.Reader
-	if body, _, err = common.Request(url); err != nil {
+	if body, _, err = common.Request(ctx, url); err != nil {
 		if printDebug {
 			fmt.Fprintf(
 				os.Stderr,
@@ -135,12 +136,20 @@ func (p *Package) updateFromPackage(p2 Package) {
 	p.Licenses = p2.Licenses
 }
 
+// SourceName returns the name of the source of the package.
+func (p Package) SourceName() string {
+	if p.Source == "" {
+		return DefaultSource
+	}
+	return p.Source
+}
+
 // String returns the string representation of a package.
 func (p Package) String() string {
 	var w strings.Builder
 	fmt.Fprint(
 		&w,
-		format.Apply("kcp/", "l_majenta"),
+		format.Apply(p.SourceName()+"/", "l_majenta"),
 		format.Apply(p.Name, "bold"),
 		" ",
 		format.Apply(p.RepoVersion, "l_green"),
@@ -148,13 +157,25 @@ func (p Package) String() string {
 
 	if p.LocalVersion != "" {
 		fmt.Fprint(&w, " ")
-		if p.LocalVersion == p.RepoVersion {
+		switch alpm.VerCmp(p.LocalVersion, p.RepoVersion) {
+		case 0:
 			format.FormatOf("l_cyan").Fprint(&w, common.Tr(labelInstalled))
-		} else {
+		case 1:
+			format.FormatOf("l_yellow").Fprintf(&w, common.Tr(labelInstalledNewer), p.LocalVersion)
+		default:
 			format.FormatOf("l_cyan").Fprintf(&w, common.Tr(labelInstalledVersion), p.LocalVersion)
 		}
 	}
 
+	if p.Hold != nil {
+		fmt.Fprint(&w, " ")
+		if p.Hold.IsPin() {
+			format.FormatOf("l_red").Fprintf(&w, common.Tr(labelPinnedMark), p.Hold.String())
+		} else {
+			format.FormatOf("l_red").Fprint(&w, common.Tr(labelHeldMark))
+		}
+	}
+
 	format.FormatOf("l_blue").Fprintf(&w, " (%d)", p.Stars)
 	fmt.Fprint(&w, "\n\t", p.Description)
 
@@ -163,7 +184,7 @@ func (p Package) String() string {
 
 // Detail returns detailled informations of the package.
 func (p Package) Detail() string {
-	labels, values := make([]string, 14), make([]string, 14)
+	labels, values := make([]string, 16), make([]string, 16)
 
 	labels[0], values[0] = common.Tr(labelName), p.Name
 	labels[1], values[1] = common.Tr(labelVersion), p.RepoVersion
@@ -183,6 +204,15 @@ func (p Package) Detail() string {
 		values[12] = common.Tr(labelYes)
 	}
 	labels[13], values[13] = common.Tr(labelValidatedBy), p.ValidatedBy
+	labels[14], values[14] = common.Tr(labelSource), p.SourceName()
+	labels[15], values[15] = common.Tr(labelHeld), common.Tr(labelNo)
+
+	if p.Hold != nil {
+		values[15] = common.Tr(labelYes)
+		if p.Hold.IsPin() {
+			values[15] = p.Hold.String()
+		}
+	}
 
 	s := 0
 	for _, l := range labels {
@@ -205,31 +235,6 @@ func (p Package) Detail() string {
 	return strings.Join(result, "\n")
 }
 
-// Clone clone the git repo corresponding to the package
-// on the given dir.
-// if ssh it clones using ssh.
-func (p Package) Clone(dir string, ssh bool) (fullDir string, err error) {
-	fullDir = path.Join(dir, p.Name)
-	if common.FileExists(fullDir) {
-		err = errors.New(common.Tr(errPathExists, fullDir))
-		return
-	}
-	if err = os.Chdir(dir); err != nil {
-		return
-	}
-
-	url := p.CloneUrl
-	if ssh {
-		url = p.SshUrl
-	}
-
-	if err = common.LaunchCommand("git", "clone", url); err != nil {
-		os.RemoveAll(fullDir)
-	}
-
-	return
-}
-
 // FilterFunc represents a function to filter a list of packages.
 // Only packages which pass the function will be kept.
 type FilterFunc func(Package) bool
@@ -287,9 +292,26 @@ func FilterInstalled(p Package) bool {
 	return p.LocalVersion != ""
 }
 
-// FilterOutdated keeps only packages which need to be updated.
+// FilterOutdated keeps only packages which need to be updated,
+// ie. the installed version is older than the version of the repo.
 func FilterOutdated(p Package) bool {
-	return FilterInstalled(p) && p.LocalVersion != p.RepoVersion
+	return FilterInstalled(p) && alpm.VerCmp(p.LocalVersion, p.RepoVersion) < 0
+}
+
+// FilterNewer keeps only packages whose installed version
+// is newer than the version of the repo.
+func FilterNewer(p Package) bool {
+	return FilterInstalled(p) && alpm.VerCmp(p.LocalVersion, p.RepoVersion) > 0
+}
+
+// FilterHeld keeps only packages whose upgrades are held back.
+func FilterHeld(p Package) bool {
+	return p.Hold != nil
+}
+
+// FilterNotHeld keeps only packages whose upgrades are not held back.
+func FilterNotHeld(p Package) bool {
+	return p.Hold == nil
 }
 
 // FilterStarred filter packages which have a star or more.
@@ -305,6 +327,18 @@ func (pl *Packages) Push(packages ...Package) {
 	*pl = append(*pl, packages...)
 }
 
+// Set replaces the entry with the same name than the given package.
+// If the list doesn’t contain it, the package is appended.
+func (pl *Packages) Set(p Package) {
+	for i, e := range *pl {
+		if e.Name == p.Name {
+			(*pl)[i] = p
+			return
+		}
+	}
+	pl.Push(p)
+}
+
 // Remove removes the given entries from the list.
 func (pl *Packages) Remove(packages ...Package) {
 	packageNames := collection.NewSet[string]()
@@ -362,60 +396,53 @@ func (pl Packages) Contains(name string) (ok bool) {
 
 // SearchBroken returns packages which have at least
 // one depend missing on the offical repo or on KCP.
-func (pl Packages) SearchBroken() []string {
-	done := concurrent.NewSet(common.Exceptions...)
+func (pl Packages) SearchBroken() (broken []string) {
+	done := collection.NewSet(common.Exceptions...)
 	for _, p := range pl {
 		done.Add(p.Name)
 	}
 
-	broken := concurrent.NewSlice[string]()
-	cleanDep := func(d string) string {
-		for _, s := range []string{">", "<", "=", ":"} {
-			if i := strings.Index(d, s); i > 0 {
-				d = d[:i]
+	var depends []string
+	for _, p := range pl {
+		for _, l := range [][]string{p.Depends, p.OptDepends, p.MakeDepends} {
+			for _, d := range l {
+				d = alpm.DependName(d)
+				if len(d) == 0 || done.Contains(d) {
+					continue
+				}
+				done.Add(d)
+				depends = append(depends, d)
 			}
 		}
-		return strings.TrimSpace(d)
 	}
-	checkBroken := func(d string) {
-		d = cleanDep(d)
-		if len(d) == 0 || done.Contains(d) {
-			return
+
+	// If the sync databases are readable, the checks are done in memory.
+	// Otherwise, each check launches pacman, so they are done in parallel.
+	inRepo := make([]bool, len(depends))
+	if _, err := common.SyncDatabases(); err == nil {
+		for i, d := range depends {
+			inRepo[i] = common.IsInRepo(d)
 		}
-		done.Add(d)
-		result, _ := common.GetOutputCommand("pacman", "-Si", d)
-		if len(result) == 0 {
-			broken.Append(d)
+	} else {
+		sem := make(chan struct{}, defaultRoutines)
+		var wg sync.WaitGroup
+		for i, d := range depends {
+			sem <- struct{}{}
+			wg.Go(func() {
+				defer func() { <-sem }()
+				inRepo[i] = common.IsInRepo(d)
+			})
 		}
+		wg.Wait()
 	}
 
-	buffer := make(chan string, max(100, len(pl)))
-	var wg sync.WaitGroup
-
-	for range defaultRoutines {
-		wg.Go(func() {
-			for {
-				if d, ok := <-buffer; ok {
-					checkBroken(d)
-				} else {
-					return
-				}
-			}
-		})
-	}
-
-	for _, p := range pl {
-		for _, depends := range [][]string{p.Depends, p.OptDepends, p.MakeDepends} {
-			for _, d := range depends {
-				buffer <- d
-			}
+	for i, d := range depends {
+		if !inRepo[i] {
+			broken = append(broken, d)
 		}
 	}
 
-	close(buffer)
-	wg.Wait()
-
-	return broken.CloseData()
+	return
 }
 
 // Names returns the list of the packages’ names.
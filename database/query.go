@@ -0,0 +1,379 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"codeberg.org/bvaudour/kcp/alpm"
+	"codeberg.org/bvaudour/kcp/common"
+)
+
+// Operators of a query term.
+const (
+	opMatch = ":"
+	opEqual = "="
+	opGt    = ">"
+	opGe    = ">="
+	opLt    = "<"
+	opLe    = "<="
+)
+
+var queryOperators = []string{opGe, opLe, opMatch, opEqual, opGt, opLt}
+
+type queryField struct {
+	values  func(Package) []string
+	number  func(Package) int
+	depends bool
+	version bool
+}
+
+var queryFields = map[string]queryField{
+	"name":        {values: func(p Package) []string { return []string{p.Name} }},
+	"description": {values: func(p Package) []string { return []string{p.Description} }},
+	"version":     {values: func(p Package) []string { return []string{p.RepoVersion} }, version: true},
+	"local":       {values: func(p Package) []string { return []string{p.LocalVersion} }, version: true},
+	"url":         {values: func(p Package) []string { return []string{p.Url} }},
+	"source":      {values: func(p Package) []string { return []string{p.SourceName()} }},
+	"arch":        {values: func(p Package) []string { return p.Arch }},
+	"license":     {values: func(p Package) []string { return p.Licenses }},
+	"depends":     {values: func(p Package) []string { return p.Depends }, depends: true},
+	"makedepends": {values: func(p Package) []string { return p.MakeDepends }, depends: true},
+	"optdepends":  {values: func(p Package) []string { return p.OptDepends }, depends: true},
+	"provides":    {values: func(p Package) []string { return p.Provides }, depends: true},
+	"conflicts":   {values: func(p Package) []string { return p.Conflicts }, depends: true},
+	"replaces":    {values: func(p Package) []string { return p.Replaces }, depends: true},
+	"stars":       {number: func(p Package) int { return p.Stars }},
+	"is":          {},
+}
+
+var queryAliases = map[string]string{
+	"desc":     "description",
+	"licenses": "license",
+	"depend":   "depends",
+	"provide":  "provides",
+	"star":     "stars",
+}
+
+var queryStates = map[string]FilterFunc{
+	"installed": FilterInstalled,
+	"outdated":  FilterOutdated,
+	"newer":     FilterNewer,
+	"held":      FilterHeld,
+	"starred":   FilterStarred,
+}
+
+// QueryFields returns the names of the fields
+// which can be used in a query.
+func QueryFields() []string {
+	fields := make([]string, 0, len(queryFields))
+	for f := range queryFields {
+		fields = append(fields, f)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+type queryToken struct {
+	value  string
+	quoted bool
+}
+
+func (t queryToken) is(keywords ...string) bool {
+	return !t.quoted && slices.ContainsFunc(keywords, func(k string) bool {
+		return strings.EqualFold(t.value, k)
+	})
+}
+
+func tokenizeQuery(query string) (tokens []queryToken, err error) {
+	var current strings.Builder
+	var quote rune
+	var inToken, quoted, escaped bool
+	var depth int // Number of parenthesis opened inside the current term.
+	flush := func() {
+		if inToken {
+			tokens = append(tokens, queryToken{current.String(), quoted})
+		}
+		current.Reset()
+		inToken, quoted, escaped, depth = false, false, false, 0
+	}
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '"' || r == '\'':
+			quote, quoted = r, quoted || !inToken
+			inToken = true
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' && !inToken:
+			tokens = append(tokens, queryToken{value: "("})
+		case r == ')' && depth == 0:
+			flush()
+			tokens = append(tokens, queryToken{value: ")"})
+		case r == '(':
+			// Parenthesis inside a term belong to it (eg. name:^(qt|kf)6).
+			current.WriteRune(r)
+			depth++
+		case r == ')':
+			current.WriteRune(r)
+			depth--
+		case (r == '!' || r == '-') && !inToken:
+			tokens = append(tokens, queryToken{value: "!"})
+		default:
+			current.WriteRune(r)
+			inToken, escaped = true, r == '\\'
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New(common.Tr(errQueryQuote))
+	}
+	flush()
+
+	return
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (qp *queryParser) peek() (t queryToken, ok bool) {
+	if ok = qp.pos < len(qp.tokens); ok {
+		t = qp.tokens[qp.pos]
+	}
+	return
+}
+
+func (qp *queryParser) next() (t queryToken, ok bool) {
+	if t, ok = qp.peek(); ok {
+		qp.pos++
+	}
+	return
+}
+
+func (qp *queryParser) parseOr() (FilterFunc, error) {
+	f, err := qp.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := []FilterFunc{f}
+	for {
+		t, ok := qp.peek()
+		if !ok || !t.is("or", "||") {
+			break
+		}
+		qp.next()
+		if f, err = qp.parseAnd(); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return func(p Package) bool {
+		return slices.ContainsFunc(filters, func(f FilterFunc) bool { return f(p) })
+	}, nil
+}
+
+func (qp *queryParser) parseAnd() (FilterFunc, error) {
+	var filters []FilterFunc
+	for {
+		t, ok := qp.peek()
+		if !ok || t.is(")", "or", "||") {
+			break
+		}
+		if t.is("and", "&&") {
+			qp.next()
+			continue
+		}
+		f, err := qp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 0 {
+		return nil, errors.New(common.Tr(errQueryEmpty))
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return NewFilter(filters...), nil
+}
+
+func (qp *queryParser) parseUnary() (FilterFunc, error) {
+	t, ok := qp.next()
+	switch {
+	case !ok || t.is("or", "||", "and", "&&"):
+		// A negation without term.
+		return nil, errors.New(common.Tr(errQueryEmpty))
+	case t.is("!", "not"):
+		f, err := qp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(p Package) bool { return !f(p) }, nil
+	case t.is("("):
+		f, err := qp.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := qp.next(); !ok || !t.is(")") {
+			return nil, errors.New(common.Tr(errQueryParenthesis))
+		}
+		return f, nil
+	case t.is(")"):
+		return nil, errors.New(common.Tr(errQueryParenthesis))
+	}
+	return parseQueryTerm(t)
+}
+
+func splitQueryTerm(term string) (field, op, value string) {
+	i := strings.IndexFunc(term, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if i <= 0 {
+		return "", "", term
+	}
+	for _, o := range queryOperators {
+		if strings.HasPrefix(term[i:], o) {
+			return strings.ToLower(term[:i]), o, term[i+len(o):]
+		}
+	}
+	return "", "", term
+}
+
+func parseQueryTerm(t queryToken) (FilterFunc, error) {
+	field, op, value := splitQueryTerm(t.value)
+	if t.quoted || op == "" {
+		search := strings.ToLower(t.value)
+		return func(p Package) bool {
+			return strings.Contains(strings.ToLower(p.Name), search) ||
+				strings.Contains(strings.ToLower(p.Description), search)
+		}, nil
+	}
+
+	if alias, ok := queryAliases[field]; ok {
+		field = alias
+	}
+	qf, ok := queryFields[field]
+	switch {
+	case !ok:
+		return nil, errors.New(common.Tr(errQueryField, field, strings.Join(QueryFields(), ", ")))
+	case field == "is":
+		f, ok := queryStates[strings.ToLower(value)]
+		if !ok || (op != opMatch && op != opEqual) {
+			return nil, errors.New(common.Tr(errQueryValue, t.value))
+		}
+		return f, nil
+	case qf.number != nil:
+		return numberFilter(qf, op, value, t.value)
+	}
+
+	var match func(string) bool
+	switch op {
+	case opMatch:
+		re, err := regexp.Compile("(?i)" + value)
+		if err != nil {
+			return nil, errors.New(common.Tr(errQueryRegexp, value, err))
+		}
+		match = re.MatchString
+	case opEqual:
+		match = func(v string) bool { return strings.EqualFold(v, value) }
+	default:
+		if !qf.version {
+			return nil, errors.New(common.Tr(errQueryOperator, op, field))
+		}
+		match = versionMatcher(op, value)
+	}
+
+	return func(p Package) bool {
+		return slices.ContainsFunc(qf.values(p), func(v string) bool {
+			if qf.depends {
+				v = alpm.DependName(v)
+			}
+			return match(v)
+		})
+	}, nil
+}
+
+func versionMatcher(op, value string) func(string) bool {
+	var cmp func(int) bool
+	switch op {
+	case opGt:
+		cmp = func(c int) bool { return c > 0 }
+	case opGe:
+		cmp = func(c int) bool { return c >= 0 }
+	case opLt:
+		cmp = func(c int) bool { return c < 0 }
+	case opLe:
+		cmp = func(c int) bool { return c <= 0 }
+	}
+	return func(v string) bool { return v != "" && cmp(alpm.VerCmp(v, value)) }
+}
+
+func numberFilter(qf queryField, op, value, term string) (FilterFunc, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, errors.New(common.Tr(errQueryValue, term))
+	}
+	var cmp func(int) bool
+	switch op {
+	case opMatch, opEqual:
+		cmp = func(v int) bool { return v == n }
+	case opGt:
+		cmp = func(v int) bool { return v > n }
+	case opGe:
+		cmp = func(v int) bool { return v >= n }
+	case opLt:
+		cmp = func(v int) bool { return v < n }
+	case opLe:
+		cmp = func(v int) bool { return v <= n }
+	}
+	return func(p Package) bool { return cmp(qf.number(p)) }, nil
+}
+
+// ParseQuery parses a search query and returns the corresponding filter.
+//
+// A query is a list of terms, implicitly joined by AND.
+// Terms can be joined by OR, negated by NOT (or a leading ! or -)
+// and grouped with parenthesis. A parenthesis opened inside a term
+// belongs to the term until it is closed, so regexps like name:^(qt|kf)6
+// don't need to be quoted. A term can be:
+//   - a bare word, which matches the name or the description (case insensitive),
+//   - field:regexp, which matches if one of the values of the field matches the regexp,
+//   - field=value, which matches if one of the values of the field equals the value,
+//   - stars>n (or >=, <, <=, =) to compare the number of stars,
+//   - version>v or local>v (or >=, <, <=) to compare versions like pacman does,
+//   - is:installed, is:outdated, is:newer, is:held or is:starred to match the state of the package.
+//
+// For depends, makedepends, optdepends, provides, conflicts and replaces,
+// the values are compared without the version constraints.
+func ParseQuery(query string) (FilterFunc, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	qp := &queryParser{tokens: tokens}
+	f, err := qp.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := qp.peek(); ok {
+		return nil, errors.New(common.Tr(errQueryParenthesis))
+	}
+	return f, nil
+}
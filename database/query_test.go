@@ -0,0 +1,157 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+var queryPackages = Packages{
+	{
+		Name:         "qt6-base",
+		Description:  "A cross-platform application and UI framework",
+		RepoVersion:  "6.5.1-1",
+		LocalVersion: "6.5.0-1",
+		Stars:        12,
+		Arch:         []string{"x86_64"},
+		Depends:      []string{"glibc>=2.38", "zlib"},
+	},
+	{
+		Name:         "kf6-kio",
+		Description:  "Resource and network access abstraction",
+		RepoVersion:  "6.2.0-1",
+		LocalVersion: "6.2.0-1",
+		Stars:        3,
+		Depends:      []string{"qt6-base>=6.5"},
+		Provides:     []string{"kio=6"},
+	},
+	{
+		Name:        "yay-git",
+		Description: "Yet another yogurt, built from git",
+		RepoVersion: "12.0-1",
+	},
+}
+
+func TestTokenizeQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		tokens []queryToken
+	}{
+		{"qt", []queryToken{{"qt", false}}},
+		{"  qt   kf6 ", []queryToken{{"qt", false}, {"kf6", false}}},
+		{`"yet another"`, []queryToken{{"yet another", true}}},
+		{`name:'a b'`, []queryToken{{"name:a b", false}}},
+		{"-git", []queryToken{{"!", false}, {"git", false}}},
+		{"!qt", []queryToken{{"!", false}, {"qt", false}}},
+		{"yay-git", []queryToken{{"yay-git", false}}},
+		{`"-git"`, []queryToken{{"-git", true}}},
+		{"(a or b)", []queryToken{{"(", false}, {"a", false}, {"or", false}, {"b", false}, {")", false}}},
+		{`"or"`, []queryToken{{"or", true}}},
+		{"name:^(qt|kf)6", []queryToken{{"name:^(qt|kf)6", false}}},
+		{"(name:^(qt|kf)6)", []queryToken{{"(", false}, {"name:^(qt|kf)6", false}, {")", false}}},
+		{`name:\(x`, []queryToken{{`name:\(x`, false}}},
+		{"qt)(kf", []queryToken{{"qt", false}, {")", false}, {"(", false}, {"kf", false}}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := tokenizeQuery(tt.query)
+		if err != nil {
+			t.Errorf("tokenizeQuery(%q): %v", tt.query, err)
+		} else if !slices.Equal(tokens, tt.tokens) {
+			t.Errorf("tokenizeQuery(%q) = %v, want %v", tt.query, tokens, tt.tokens)
+		}
+	}
+
+	if _, err := tokenizeQuery(`"unterminated`); err == nil {
+		t.Error("tokenizeQuery: unterminated quote accepted")
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		names []string
+	}{
+		// Bare words match the name or the description.
+		{"qt", []string{"qt6-base"}},
+		{"NETWORK", []string{"kf6-kio"}},
+		{`"yet another"`, []string{"yay-git"}},
+
+		// Operators and precedence: AND binds tighter than OR.
+		{"qt kio", nil},
+		{"qt or kio", []string{"qt6-base", "kf6-kio"}},
+		{"qt || kio && yay", []string{"qt6-base"}},
+		{"(qt or kio) and is:installed", []string{"qt6-base", "kf6-kio"}},
+		{"yay or qt6 is:outdated", []string{"qt6-base", "yay-git"}},
+
+		// Negations.
+		{"-git", []string{"qt6-base", "kf6-kio"}},
+		{"!git !qt", []string{"kf6-kio"}},
+		{"not (qt or kio)", []string{"yay-git"}},
+		{"- - git", []string{"yay-git"}},
+
+		// Fields.
+		{"name:^kf6", []string{"kf6-kio"}},
+		{"name=QT6-BASE", []string{"qt6-base"}},
+		{"desc:framework", []string{"qt6-base"}},
+		{"arch=x86_64", []string{"qt6-base"}},
+		{"depends=glibc", []string{"qt6-base"}},
+		{"depends:^qt6", []string{"kf6-kio"}},
+		{"provide=kio", []string{"kf6-kio"}},
+		{"name:^(qt|kf)6", []string{"qt6-base", "kf6-kio"}},
+		{"(name:^(qt|kf)6 -is:outdated)", []string{"kf6-kio"}},
+
+		// Versions are compared like pacman does.
+		{"version>=6.5", []string{"qt6-base", "yay-git"}},
+		{"version<6.5.1", []string{"kf6-kio"}},
+		{"version>6.10", []string{"yay-git"}},
+		{"local>=6.5.0", []string{"qt6-base"}},
+		{"local<6.5", []string{"kf6-kio"}},
+
+		// Stars are compared as numbers.
+		{"stars>3", []string{"qt6-base"}},
+		{"stars>=3", []string{"qt6-base", "kf6-kio"}},
+		{"stars=0", []string{"yay-git"}},
+		{"stars<3", []string{"yay-git"}},
+
+		// States.
+		{"is:installed", []string{"qt6-base", "kf6-kio"}},
+		{"is:outdated", []string{"qt6-base"}},
+		{"is:starred -is:outdated", []string{"kf6-kio"}},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if names := queryPackages.Filter(f).Names(); !slices.Equal(names, tt.names) {
+			t.Errorf("ParseQuery(%q) matches %v, want %v", tt.query, names, tt.names)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"(qt",
+		"qt)",
+		"qt or",
+		"-",
+		"qt !",
+		"not",
+		"- or qt",
+		"foo:bar",
+		"is:unknown",
+		"is>installed",
+		"stars>many",
+		"name>qt",
+		"name:(",
+		"(name:(qt)",
+		`"qt`,
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%q): no error", query)
+		}
+	}
+}
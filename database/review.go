@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// ReviewedFile is a file of a package, as approved by the user.
+type ReviewedFile struct {
+	Hash    string `json:"sha256"`
+	Content string `json:"content"`
+}
+
+// Review is the revision of the files of a package
+// (PKGBUILD and install files) approved by the user.
+type Review struct {
+	Commit     string                  `json:"commit"`
+	ApprovedAt time.Time               `json:"approved_at"`
+	Files      map[string]ReviewedFile `json:"files"`
+}
+
+// Reviews are the approved reviews indexed by package name.
+type Reviews map[string]Review
+
+// NewReview reads the files to review of the given clone of a package.
+func NewReview(dir string) (review Review, err error) {
+	files := []string{"PKGBUILD"}
+	m, _ := filepath.Glob(filepath.Join(dir, "*.install"))
+	for _, f := range m {
+		files = append(files, filepath.Base(f))
+	}
+
+	review.Files = make(map[string]ReviewedFile)
+	for _, name := range files {
+		var b []byte
+		if b, err = os.ReadFile(filepath.Join(dir, name)); err != nil {
+			return
+		}
+		h := sha256.Sum256(b)
+		review.Files[name] = ReviewedFile{
+			Hash:    hex.EncodeToString(h[:]),
+			Content: string(b),
+		}
+	}
+	review.Commit, _ = git(context.Background(), dir, "rev-parse", "HEAD")
+
+	return
+}
+
+// Changes returns the sorted names of the files which differ between
+// the approved review and the given review (modified, added or removed).
+func (r Review) Changes(current Review) (changed []string) {
+	for name, f := range current.Files {
+		if old, ok := r.Files[name]; !ok || old.Hash != f.Hash {
+			changed = append(changed, name)
+		}
+	}
+	for name := range r.Files {
+		if _, ok := current.Files[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return
+}
+
+// Diff displays the differences between the approved files
+// and the files of the given clone.
+func (r Review) Diff(dir string, names ...string) error {
+	tmp, err := os.MkdirTemp("", "kcp-review-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, name := range names {
+		approved, current := os.DevNull, filepath.Join(dir, name)
+		if f, ok := r.Files[name]; ok {
+			approved = filepath.Join(tmp, name)
+			if err = os.WriteFile(approved, []byte(f.Content), 0644); err != nil {
+				return err
+			}
+		}
+		if _, err := os.Stat(current); err != nil {
+			current = os.DevNull
+		}
+		cmd := exec.Command("git", "--no-pager", "diff", "--no-index", "--color=auto", approved, current)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		// git diff exits with 1 if there are differences.
+		var e *exec.ExitError
+		if err = cmd.Run(); err != nil && !(errors.As(err, &e) && e.ExitCode() == 1) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadReviews loads the reviews from the given file.
+// If the file doesn’t exist, no review is returned.
+func LoadReviews(fpath string) (reviews Reviews, err error) {
+	reviews = make(Reviews)
+	var b []byte
+	if b, err = os.ReadFile(fpath); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	err = json.Unmarshal(b, &reviews)
+	return
+}
+
+// SaveReviews saves atomically the reviews in the given file.
+func SaveReviews(fpath string, reviews Reviews) error {
+	b, err := json.MarshalIndent(reviews, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(fpath)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(fpath)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), fpath)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/bvaudour/kcp/common"
+)
+
+// DefaultSource is the name of the source defined
+// in the [git] section of the configuration.
+const DefaultSource = "kcp"
+
+// Source is a git server (or a local directory) providing packages.
+// If several sources provide a package with the same name,
+// the package of the source with the highest priority is used.
+type Source struct {
+	Name         string
+	Domain       string
+	Organization string
+	Connector    string
+	Auth         []string
+	Ignore       []string
+	Priority     int
+}
+
+// NewConnector returns the connector of the source.
+func (s Source) NewConnector() Connector {
+	switch ConnectorType(s.Domain, s.Connector) {
+	case ConnectorGithub:
+		return NewGithubConnector(s.Organization, s.Auth...)
+	case ConnectorGitlab:
+		return NewGitlabConnector(s.Domain, s.Organization, s.Auth...)
+	case ConnectorLocal:
+		return NewLocalConnector(s.Domain)
+	}
+	return NewForgejoConnector(s.Domain, s.Organization, s.Auth...)
+}
+
+// defaultSource returns the source defined in the [git] section.
+func defaultSource(ignored ...string) Source {
+	priority, _ := strconv.Atoi(common.Config.Get("git.priority"))
+	return Source{
+		Name:         DefaultSource,
+		Domain:       common.GitDomain,
+		Organization: common.Organization,
+		Connector:    common.GitConnector,
+		Auth:         common.GetAuthParameters(),
+		Ignore:       ignored,
+		Priority:     priority,
+	}
+}
+
+func sourceAuth(user, password, token string) []string {
+	if token != "" {
+		return []string{token}
+	} else if user != "" && password != "" {
+		return []string{user, password}
+	}
+	return nil
+}
+
+// Sources returns the sources of the configuration, sorted by priority:
+// the default source, defined by the [git] section (with the given repos
+// to ignore), then the sources listed in git.sources, each one defined
+// in a [source_<name>] section.
+func Sources(ignored ...string) []Source {
+	sources := []Source{defaultSource(ignored...)}
+
+	for _, name := range strings.Fields(common.Config.Get("git.sources")) {
+		if slices.ContainsFunc(sources, func(s Source) bool { return s.Name == name }) {
+			continue
+		}
+		get := func(key string) string { return common.Config.Get("source_" + name + "." + key) }
+		priority, _ := strconv.Atoi(get("priority"))
+		sources = append(sources, Source{
+			Name:         name,
+			Domain:       get("domain"),
+			Organization: get("organization"),
+			Connector:    get("connector"),
+			Auth:         sourceAuth(get("user"), get("password"), get("token")),
+			Ignore:       strings.Fields(get("ignore")),
+			Priority:     priority,
+		})
+	}
+
+	slices.SortStableFunc(sources, func(s1, s2 Source) int { return cmp.Compare(s2.Priority, s1.Priority) })
+	return sources
+}
+
+// UpdateSources updates the database from several sources.
+// Each source is updated as a standalone database, then
+// the packages with the same name are resolved by priority:
+// the packages of the sources with a lower priority are kept
+// apart as hidden packages.
+func (db *Database) UpdateSources(ctx context.Context, sources []Source, debug bool) (counter Counter, err error) {
+	startTime := time.Now()
+	all := append(slices.Clone(db.Packages), db.Hidden...)
+
+	var remote Packages
+	for _, s := range sources {
+		sub := Database{
+			LastUpdate:  db.LastUpdate,
+			IgnoreRepos: s.Ignore,
+			Packages:    all.Filter(func(p Package) bool { return p.Source == s.Name }),
+		}
+		var c Counter
+		if c, err = sub.UpdateRemote(ctx, s.NewConnector(), debug); err != nil {
+			return
+		}
+		counter.Added += c.Added
+		counter.Updated += c.Updated
+		counter.Deleted += c.Deleted
+		for _, p := range sub.Packages {
+			p.Source = s.Name
+			remote = append(remote, p)
+		}
+	}
+
+	// The sources are sorted by priority,
+	// so the first package of a name is the one to use.
+	var packages, hidden Packages
+	seen := make(map[string]bool)
+	for _, p := range remote {
+		if seen[p.Name] {
+			hidden = append(hidden, p)
+		} else {
+			seen[p.Name] = true
+			packages = append(packages, p)
+		}
+	}
+
+	db.Packages, db.Hidden, db.LastUpdate = packages, hidden, startTime
+	db.UpdateBroken()
+
+	return
+}
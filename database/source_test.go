@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestUpdateSources(t *testing.T) {
+	main, extra := t.TempDir(), t.TempDir()
+	newRepo(t, main, "kcptest-common", date2020, "1.0")
+	newRepo(t, main, "kcptest-main", date2020, "1.0")
+	newRepo(t, extra, "kcptest-common", date2020, "2.0")
+	newRepo(t, extra, "kcptest-extra", date2020, "2.0")
+	newRepo(t, extra, "kcptest-ignored", date2020, "2.0")
+
+	// The sources are sorted by priority.
+	sources := []Source{
+		{Name: "extra", Domain: extra, Priority: 10, Ignore: []string{"kcptest-ignored"}},
+		{Name: DefaultSource, Domain: main},
+	}
+
+	db := New()
+	counter, err := db.UpdateSources(context.Background(), sources, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.Added != 4 {
+		t.Errorf("counter: %+v", counter)
+	}
+
+	db.Sort(SortByName)
+	if names := db.Names(); !slices.Equal(names, []string{"kcptest-common", "kcptest-extra", "kcptest-main"}) {
+		t.Errorf("names: %v", names)
+	}
+	for name, source := range map[string]string{
+		"kcptest-common": "extra",
+		"kcptest-extra":  "extra",
+		"kcptest-main":   DefaultSource,
+	} {
+		if p, _ := db.Get(name); p.Source != source {
+			t.Errorf("source of %s: %q, want %q", name, p.Source, source)
+		}
+	}
+	if common, _ := db.Get("kcptest-common"); common.RepoVersion != "2.0-1" {
+		t.Errorf("version of kcptest-common: %q", common.RepoVersion)
+	}
+
+	// The package of the source with the lowest priority is hidden.
+	if len(db.Hidden) != 1 || db.Hidden[0].Name != "kcptest-common" || db.Hidden[0].Source != DefaultSource {
+		t.Errorf("hidden packages: %+v", db.Hidden)
+	}
+}
@@ -3,6 +3,9 @@ package database
 const (
 	labelInstalled        = "[installed]"
 	labelInstalledVersion = "[installed: %s]"
+	labelInstalledNewer   = "[installed: %s (newer)]"
+	labelHeldMark         = "[held]"
+	labelPinnedMark       = "[held: %s]"
 	labelName             = "Name"
 	labelVersion          = "Version"
 	labelDescription      = "Description"
@@ -17,6 +20,8 @@ const (
 	labelReplaces         = "Replaces"
 	labelInstall          = "Install Script"
 	labelValidatedBy      = "Validated By"
+	labelSource           = "Source"
+	labelHeld             = "Held"
 	labelYes              = "Yes"
 	labelNo               = "No"
 
@@ -25,6 +30,23 @@ const (
 	errFailedGetPKGBUILD              = "Failed to get PKGBUILD for %s: %v"
 	errCountHeader                    = "Could not parse X-Total-Count header: %v"
 	errCountHeaderNotFound            = "X-Total-Count not found in response"
+	errPackageNotFound                = "Package %s not found"
+	errDependsCycle                   = "Dependency cycle detected: %s"
+	errMissingDepends                 = "%s: dependencies neither in the official repos nor in KCP: %s"
+	errQueryQuote                     = "Invalid query: unterminated quote"
+	errQueryEmpty                     = "Invalid query: missing term"
+	errQueryParenthesis               = "Invalid query: unbalanced parenthesis"
+	errQueryField                     = "Invalid query: unknown field '%s' (available fields: %s)"
+	errQueryValue                     = "Invalid query: bad value in '%s'"
+	errQueryOperator                  = "Invalid query: operator '%s' cannot be used with field '%s'"
+	errQueryRegexp                    = "Invalid query: bad regular expression '%s': %v"
+	errDbVersion                      = "Database %s has version %d, which is not supported (max supported version: %d)"
+	errDbCorrupted                    = "Failed to decode the database %s: %v (file moved to %s)"
+	errDbRecovered                    = "Failed to decode the database %s: %v (restored from backup %s)"
+	errPinVersion                     = "No commit found with version %s"
+	errPinCheckout                    = "Failed to check out the pin %s: %v"
+	errClone                          = "Failed to clone %s: %s"
+	errFetch                          = "Failed to update the clone of %s (the cached revision is used): %v"
 
 	msgAdded   = "%d entries added!"
 	msgDeleted = "%d entries deleted!"
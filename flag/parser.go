@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -212,6 +213,13 @@ func (p *Parser) Require(name0 string, names ...string) int {
 	return len(flags)
 }
 
+// isNegativeNumber checks if the arg is a negative number,
+// which is a value and not a flag.
+func isNegativeNumber(a string) bool {
+	_, err := strconv.Atoi(a)
+	return err == nil && a[0] == '-'
+}
+
 func format(args []string) []string {
 	out := make([]string, 0, len(args))
 	for _, a := range args {
@@ -221,6 +229,8 @@ func format(args []string) []string {
 		switch {
 		case l == 0:
 			continue
+		case isNegativeNumber(a):
+			out = append(out, a)
 		case l > 2 && a[0] == '-':
 			if a[1] == '-' {
 				i := strings.Index(a, "=")
@@ -264,6 +274,8 @@ func (p *Parser) Parse(args []string) error {
 		switch {
 		case a[0] != '-':
 			continue
+		case isNegativeNumber(a) && !p.ContainsFlag(a):
+			continue
 		case !p.ContainsFlag(a):
 			return NewError(errUnsupportedFlag, a)
 		default:
@@ -8,6 +8,10 @@ require (
 	git.kaosx.ovh/benjamin/format v1.1.1 // indirect
 	git.kaosx.ovh/benjamin/ini v1.1.0 // indirect
 	git.kaosx.ovh/benjamin/option v1.0.0 // indirect
+	github.com/klauspost/compress v1.18.0
 	github.com/leonelquinteros/gotext v1.7.2 // indirect
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/term v0.45.0
 	mvdan.cc/sh/v3 v3.12.0 // indirect
 )
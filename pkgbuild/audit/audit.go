@@ -0,0 +1,447 @@
+// Package audit provides a static analysis of the PKGBUILDs,
+// to detect the risky constructs before building a package.
+package audit
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"codeberg.org/bvaudour/kcp/common"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Severity is the severity of a finding.
+type Severity int
+
+const (
+	Suspicious Severity = iota
+	Dangerous
+)
+
+func (s Severity) String() string {
+	if s == Dangerous {
+		return common.Tr(labelDangerous)
+	}
+	return common.Tr(labelSuspicious)
+}
+
+// Finding is a risky construct found in the PKGBUILD.
+// Function is the name of the function containing
+// the construct (empty for the global scope).
+type Finding struct {
+	Severity Severity
+	Function string
+	Line     int
+	Code     string
+	Text     string
+}
+
+// Scope returns the human-readable scope of the finding.
+func (f Finding) Scope() string {
+	if f.Function == "" {
+		return common.Tr(labelGlobal)
+	}
+	return f.Function + "()"
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s (L.%d, %s): %s\n  %s", f.Severity, f.Line, f.Scope(), f.Text, f.Code)
+}
+
+// Findings is a list of findings.
+type Findings []Finding
+
+// Count returns the number of findings of the given severity.
+func (fl Findings) Count(severity Severity) (count int) {
+	for _, f := range fl {
+		if f.Severity == severity {
+			count++
+		}
+	}
+	return
+}
+
+const maxCodeLen = 100
+
+var (
+	shells      = []string{"sh", "bash", "zsh", "dash", "ksh", "fish", "python", "python3", "perl", "ruby", "node", "php", "lua", "source", ".", "eval"}
+	elevators   = []string{"sudo", "doas", "su", "pkexec", "run0"}
+	wrappers    = []string{"sudo", "doas", "env", "command", "exec", "nohup", "time", "builtin"}
+	downloaders = []string{"curl", "wget", "fetch", "aria2c", "axel"}
+	network     = []string{"curl", "wget", "fetch", "aria2c", "axel", "ftp", "nc", "ncat", "netcat", "rsync", "scp", "sftp", "ssh", "svn", "hg"}
+	netSubCmds  = map[string][]string{
+		"git":   {"clone", "fetch", "pull", "ls-remote", "submodule"},
+		"npm":   {"install", "ci", "add"},
+		"yarn":  {"install", "add"},
+		"pnpm":  {"install", "add"},
+		"pip":   {"install", "download"},
+		"pip3":  {"install", "download"},
+		"cargo": {"fetch", "install"},
+		"go":    {"get", "mod"},
+	}
+	writers    = []string{"mkdir", "touch", "tee", "chmod", "chown", "truncate", "sed"}
+	copiers    = []string{"cp", "mv", "install", "ln", "rsync"}
+	buildDirs  = []string{"srcdir", "pkgdir", "startdir"}
+	safeOutput = []string{"/dev/null", "/dev/stdout", "/dev/stderr", "/dev/fd/", "/proc/self/fd/"}
+
+	rEncoded = regexp.MustCompile(`[A-Za-z0-9+/]{80,}={0,2}`)
+	rHex     = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
+
+// wordString returns the value of the word
+// if it contains only literals.
+func wordString(w *syntax.Word) (string, bool) {
+	var sb strings.Builder
+	for _, part := range w.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			sb.WriteString(p.Value)
+		case *syntax.SglQuoted:
+			sb.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			for _, dp := range p.Parts {
+				lit, ok := dp.(*syntax.Lit)
+				if !ok {
+					return "", false
+				}
+				sb.WriteString(lit.Value)
+			}
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// command returns the name of the command really executed
+// by the call (ie. without the wrappers like sudo or env)
+// and its arguments.
+func command(call *syntax.CallExpr) (name string, args []*syntax.Word) {
+	args = call.Args
+	for len(args) > 0 {
+		s, ok := wordString(args[0])
+		if !ok {
+			return "", nil
+		}
+		name, args = filepath.Base(s), args[1:]
+		if !slices.Contains(wrappers, name) {
+			return
+		}
+		for len(args) > 0 {
+			if s, ok := wordString(args[0]); !ok || !(strings.HasPrefix(s, "-") || strings.Contains(s, "=")) {
+				break
+			}
+			args = args[1:]
+		}
+	}
+	return "", nil
+}
+
+// operands returns the arguments which are not options.
+func operands(args []*syntax.Word) (out []*syntax.Word) {
+	for _, a := range args {
+		if s, ok := wordString(a); ok && strings.HasPrefix(s, "-") {
+			continue
+		}
+		out = append(out, a)
+	}
+	return
+}
+
+// hasOption checks if one of the arguments is the given long option
+// or a short options group containing one of the given letters.
+func hasOption(args []*syntax.Word, long string, short string) bool {
+	for _, a := range args {
+		s, ok := wordString(a)
+		switch {
+		case !ok:
+		case s == long:
+			return true
+		case len(s) > 1 && s[0] == '-' && s[1] != '-' && strings.ContainsAny(s[1:], short):
+			return true
+		}
+	}
+	return false
+}
+
+// isDecoder checks if the command decodes base64 data.
+func isDecoder(name string, args []*syntax.Word) bool {
+	switch name {
+	case "base64":
+		return hasOption(args, "--decode", "dD")
+	case "xxd":
+		return hasOption(args, "--revert", "r")
+	case "openssl":
+		return slices.ContainsFunc(args, func(w *syntax.Word) bool {
+			s, ok := wordString(w)
+			return ok && s == "-d"
+		})
+	}
+	return false
+}
+
+// firstParam returns the variable at the beginning of the word, if any.
+// A command substitution gives a variable named "$()".
+func firstParam(w *syntax.Word) (string, bool) {
+	if len(w.Parts) == 0 {
+		return "", false
+	}
+	part := w.Parts[0]
+	if dq, ok := part.(*syntax.DblQuoted); ok {
+		if len(dq.Parts) == 0 {
+			return "", false
+		}
+		part = dq.Parts[0]
+	}
+	switch p := part.(type) {
+	case *syntax.ParamExp:
+		return p.Param.Value, true
+	case *syntax.CmdSubst:
+		return "$()", true
+	}
+	return "", false
+}
+
+// isOutside checks if the path is outside the build dirs.
+// Only the paths which are surely outside are reported.
+func isOutside(w *syntax.Word) bool {
+	if v, ok := firstParam(w); ok {
+		return v == "HOME"
+	}
+	var first string
+	switch p := w.Parts[0].(type) {
+	case *syntax.Lit:
+		first = p.Value
+	case *syntax.SglQuoted:
+		first = p.Value
+	case *syntax.DblQuoted:
+		if len(p.Parts) > 0 {
+			if lit, ok := p.Parts[0].(*syntax.Lit); ok {
+				first = lit.Value
+			}
+		}
+	}
+	if strings.HasPrefix(first, "~") {
+		return true
+	}
+	if !strings.HasPrefix(first, "/") {
+		return false
+	}
+	return !slices.ContainsFunc(safeOutput, func(s string) bool { return strings.HasPrefix(first, s) })
+}
+
+func isPackageFunc(function string) bool {
+	return function == "package" || strings.HasPrefix(function, "package_")
+}
+
+type auditor struct {
+	findings Findings
+	function string
+}
+
+func code(node syntax.Node) string {
+	// The printer doesn’t print the redirections alone.
+	if r, ok := node.(*syntax.Redirect); ok {
+		return r.Op.String() + " " + code(r.Word)
+	}
+	var sb strings.Builder
+	syntax.NewPrinter(syntax.SingleLine(true)).Print(&sb, node)
+	s := strings.TrimSpace(sb.String())
+	if utf8.RuneCountInString(s) > maxCodeLen {
+		s = string([]rune(s)[:maxCodeLen-1]) + "…"
+	}
+	return s
+}
+
+func (a *auditor) add(severity Severity, node syntax.Node, text string) {
+	a.findings = append(a.findings, Finding{
+		Severity: severity,
+		Function: a.function,
+		Line:     int(node.Pos().Line()),
+		Code:     code(node),
+		Text:     text,
+	})
+}
+
+// fetches checks if the node contains a command which
+// downloads or decodes data.
+func fetches(node syntax.Node) (found bool) {
+	syntax.Walk(node, func(n syntax.Node) bool {
+		if call, ok := n.(*syntax.CallExpr); ok {
+			name, args := command(call)
+			if slices.Contains(downloaders, name) || isDecoder(name, args) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return
+}
+
+func (a *auditor) visitPipe(cmd *syntax.BinaryCmd) {
+	if cmd.Op != syntax.Pipe && cmd.Op != syntax.PipeAll {
+		return
+	}
+	y, ok := cmd.Y.Cmd.(*syntax.CallExpr)
+	if !ok {
+		return
+	}
+	if name, _ := command(y); slices.Contains(shells, name) && fetches(cmd.X) {
+		a.add(Dangerous, cmd, common.Tr(ruleDownloadExec, name))
+	}
+}
+
+func (a *auditor) visitCall(call *syntax.CallExpr) {
+	if len(call.Args) > 0 {
+		if s, ok := wordString(call.Args[0]); ok && slices.Contains(elevators, filepath.Base(s)) {
+			a.add(Dangerous, call, common.Tr(ruleElevation, filepath.Base(s)))
+		}
+	}
+
+	name, args := command(call)
+	switch {
+	case name == "":
+		return
+	case name == "eval":
+		a.add(Suspicious, call, common.Tr(ruleEval))
+	case isDecoder(name, args):
+		a.add(Suspicious, call, common.Tr(ruleDecode))
+	}
+
+	// sh -c "$(curl …)", bash <(curl …)
+	if slices.Contains(shells, name) {
+		for _, arg := range args {
+			if fetches(arg) {
+				a.add(Dangerous, call, common.Tr(ruleDownloadExec, name))
+				break
+			}
+		}
+	}
+
+	if name == "rm" && hasOption(args, "--recursive", "rR") {
+		for _, arg := range operands(args) {
+			if v, ok := firstParam(arg); ok && !slices.Contains(buildDirs, v) {
+				a.add(Dangerous, call, common.Tr(ruleRemoveVar, code(arg)))
+				break
+			}
+		}
+	}
+
+	a.visitWrites(call, name, args)
+
+	if isPackageFunc(a.function) {
+		sub := ""
+		if ops := operands(args); len(ops) > 0 {
+			sub, _ = wordString(ops[0])
+		}
+		if slices.Contains(network, name) || slices.Contains(netSubCmds[name], sub) {
+			a.add(Suspicious, call, common.Tr(ruleNetwork, a.function, name))
+		}
+	}
+}
+
+func (a *auditor) visitWrites(call *syntax.CallExpr, name string, args []*syntax.Word) {
+	var targets []*syntax.Word
+	ops := operands(args)
+	switch {
+	case name == "dd":
+		for _, arg := range args {
+			if s, ok := wordString(arg); ok && strings.HasPrefix(s, "of=") {
+				w, _ := syntax.NewParser().Document(strings.NewReader(strings.TrimPrefix(s, "of=")))
+				if w != nil {
+					targets = append(targets, w)
+				}
+			}
+		}
+	case name == "install" && hasOption(args, "--directory", "d"):
+		targets = ops
+	case name == "sed":
+		// Without -e, the first operand is the script.
+		if !hasOption(args, "--in-place", "i") {
+			break
+		}
+		if targets = ops; !hasOption(args, "--expression", "e") && len(ops) > 0 {
+			targets = ops[1:]
+		}
+	case slices.Contains(writers, name):
+		targets = ops
+	case slices.Contains(copiers, name) && len(ops) > 1:
+		targets = ops[len(ops)-1:]
+	}
+	for _, t := range targets {
+		if len(t.Parts) > 0 && isOutside(t) {
+			a.add(Dangerous, call, common.Tr(ruleWriteOutside, name, code(t)))
+			return
+		}
+	}
+}
+
+func (a *auditor) visitRedirect(r *syntax.Redirect) {
+	switch r.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.RdrAll, syntax.AppAll, syntax.ClbOut:
+		if r.Word != nil && len(r.Word.Parts) > 0 && isOutside(r.Word) {
+			a.add(Dangerous, r, common.Tr(ruleWriteOutside, r.Op.String(), code(r.Word)))
+		}
+	}
+}
+
+func (a *auditor) visitString(node syntax.Node, value string) {
+	for _, m := range rEncoded.FindAllString(value, -1) {
+		if !rHex.MatchString(strings.TrimRight(m, "=")) {
+			a.add(Suspicious, node, common.Tr(ruleEncoded))
+			return
+		}
+	}
+}
+
+func (a *auditor) visit(node syntax.Node) bool {
+	switch n := node.(type) {
+	case *syntax.BinaryCmd:
+		a.visitPipe(n)
+	case *syntax.CallExpr:
+		a.visitCall(n)
+	case *syntax.Redirect:
+		a.visitRedirect(n)
+	case *syntax.Lit:
+		a.visitString(n, n.Value)
+	case *syntax.SglQuoted:
+		a.visitString(n, n.Value)
+	}
+	return true
+}
+
+// Analyze returns the risky constructs of the given statements.
+func Analyze(stmts []*syntax.Stmt) Findings {
+	var a auditor
+	for _, stmt := range stmts {
+		a.function = ""
+		switch cmd := stmt.Cmd.(type) {
+		case *syntax.FuncDecl:
+			a.function = cmd.Name.Value
+		case *syntax.CallExpr:
+			if name, _ := command(cmd); len(cmd.Args) > 0 {
+				if name == "" {
+					name = code(cmd.Args[0])
+				}
+				a.add(Suspicious, cmd, common.Tr(ruleGlobalCmd, name))
+			}
+		}
+		syntax.Walk(stmt, a.visit)
+	}
+	return a.findings
+}
+
+// Parse parses the given PKGBUILD and returns its risky constructs.
+// Contrary to pkgbuild.Decode, it accepts any valid shell script.
+func Parse(r io.Reader) (Findings, error) {
+	file, err := syntax.NewParser().Parse(r, "PKGBUILD")
+	if err != nil {
+		return nil, err
+	}
+	return Analyze(file.Stmts), nil
+}
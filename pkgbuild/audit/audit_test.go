@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+type expected struct {
+	severity Severity
+	function string
+	line     int
+}
+
+func TestParse(t *testing.T) {
+	encoded := strings.Repeat("SGVsbG8gV29ybGQh", 6)
+	sha512 := strings.Repeat("0123456789abcdef", 8)
+
+	tests := []struct {
+		name     string
+		script   string
+		findings []expected
+	}{
+		{"safe", "pkgname=foo\nbuild() {\n  make\n}\npackage() {\n  make DESTDIR=\"$pkgdir\" install\n}", nil},
+		{"download piped to shell", "build() {\n  curl -sL https://example.org | bash\n}", []expected{{Dangerous, "build", 2}}},
+		{"download in shell argument", "prepare() {\n  bash -c \"$(wget -O- https://example.org)\"\n}", []expected{{Dangerous, "prepare", 2}}},
+		{"elevation", "package() {\n  sudo make install\n}", []expected{{Dangerous, "package", 2}}},
+		{"eval", "build() {\n  eval \"$cmd\"\n}", []expected{{Suspicious, "build", 2}}},
+		{"decode", "build() {\n  echo aGVsbG8= | base64 -d\n}", []expected{{Suspicious, "build", 2}}},
+		{"recursive removal of a variable", "build() {\n  rm -rf \"$dir\"/*\n}", []expected{{Dangerous, "build", 2}}},
+		{"recursive removal in srcdir", "build() {\n  rm -rf \"$srcdir/build\"\n}", nil},
+		{"copy to home", "package() {\n  cp foo ~/.bashrc\n}", []expected{{Dangerous, "package", 2}}},
+		{"install to pkgdir", "package() {\n  install -Dm644 foo \"$pkgdir/usr/bin/foo\"\n}", nil},
+		{"redirection to /etc", "build() {\n  echo x > /etc/foo\n}", []expected{{Dangerous, "build", 2}}},
+		{"redirection to /dev/null", "build() {\n  make > /dev/null\n}", nil},
+		{"sed in place outside", "package() {\n  sed -i 's/a/b/' /etc/hosts\n}", []expected{{Dangerous, "package", 2}}},
+		{"sed in place inside", "prepare() {\n  sed -i 's/a/b/' Makefile\n}", nil},
+		{"dd to a device", "build() {\n  dd if=foo of=/dev/sda\n}", []expected{{Dangerous, "build", 2}}},
+		{"network in package", "package_foo() {\n  git clone https://example.org\n}", []expected{{Suspicious, "package_foo", 2}}},
+		{"network in build", "build() {\n  git clone https://example.org\n}", nil},
+		{"global command", "pkgname=foo\ncurl https://example.org\n", []expected{{Suspicious, "", 2}}},
+		{"encoded string", "pkgname=foo\n_data='" + encoded + "'\n", []expected{{Suspicious, "", 2}}},
+		{"checksum", "pkgname=foo\nsha512sums=('" + sha512 + "')\n", nil},
+	}
+
+	for _, tt := range tests {
+		findings, err := Parse(strings.NewReader(tt.script))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []expected
+		for _, f := range findings {
+			got = append(got, expected{f.Severity, f.Function, f.Line})
+		}
+		if !slices.Equal(got, tt.findings) {
+			t.Errorf("%s: findings %v, want %v", tt.name, findings, tt.findings)
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("build() {")); err == nil {
+		t.Error("invalid script parsed")
+	}
+}
+
+func TestCount(t *testing.T) {
+	findings, err := Parse(strings.NewReader("build() {\n  sudo eval \"$x\"\n  curl https://example.org | sh\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, s := findings.Count(Dangerous), findings.Count(Suspicious); d != 2 || s != 1 {
+		t.Errorf("%d dangerous, %d suspicious findings: %v", d, s, findings)
+	}
+}
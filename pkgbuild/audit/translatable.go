@@ -0,0 +1,17 @@
+package audit
+
+const (
+	labelSuspicious = "Suspicious"
+	labelDangerous  = "Dangerous"
+	labelGlobal     = "global scope"
+
+	ruleDownloadExec = "Downloaded content is executed by %s."
+	ruleElevation    = "Privilege escalation with %s."
+	ruleWriteOutside = "%s writes outside $srcdir and $pkgdir: %s."
+	ruleRemoveVar    = "Recursive removal of a path built from a variable: %s."
+	ruleEval         = "Use of eval."
+	ruleNetwork      = "Network access in %s() with %s."
+	ruleDecode       = "Decoding of base64 data."
+	ruleEncoded      = "Long base64-like string (possibly obfuscated code)."
+	ruleGlobalCmd    = "Command %s executed at global scope."
+)
@@ -0,0 +1,311 @@
+// Package check provides the checks of a PKGBUILD according to the KCP standards.
+// The checks don’t modify the PKGBUILD: they return messages which can propose
+// fixes, and the accepted fixes are applied together at the end.
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"codeberg.org/bvaudour/kcp/common"
+	"codeberg.org/bvaudour/kcp/database"
+	"codeberg.org/bvaudour/kcp/pkgbuild"
+	"codeberg.org/bvaudour/kcp/pkgbuild/audit"
+	pformat "codeberg.org/bvaudour/kcp/pkgbuild/format"
+	"codeberg.org/bvaudour/kcp/pkgbuild/info"
+	"git.kaosx.ovh/benjamin/collection"
+	fformat "git.kaosx.ovh/benjamin/format"
+)
+
+// Level is the level of gravity of a message.
+type Level int
+
+const (
+	Info Level = iota
+	Warning
+	Error
+)
+
+var (
+	levelColor = map[Level]fformat.Format{
+		Info:    fformat.FormatOf("l_green"),
+		Warning: fformat.FormatOf("l_yellow"),
+		Error:   fformat.FormatOf("l_red"),
+	}
+)
+
+func (l Level) String() string {
+	switch l {
+	case Warning:
+		return common.Tr(typeWarning)
+	case Error:
+		return common.Tr(typeError)
+	}
+	return common.Tr(typeInfo)
+}
+
+// Fix is a correction of the PKGBUILD proposed by a message.
+type Fix struct {
+	// Question asks the user if the fix should be applied.
+	Question string
+	Default  bool
+
+	// Prompt, if not empty, asks a value to the user
+	// once the fix is accepted.
+	Prompt string
+
+	// Apply records the fix in the changes of the report.
+	Apply func(value string)
+}
+
+// Message is a result of a check.
+// Begin and End are the lines concerned by the message, if any.
+type Message struct {
+	Level      Level
+	Begin, End int
+	Text       string
+	Details    []string
+	Fix        *Fix
+}
+
+// Print writes the message in a human-readable format.
+func (m Message) Print(w io.Writer) {
+	var position string
+	if m.Begin > 0 {
+		if m.Begin == m.End {
+			position = fmt.Sprintf("(L.%d)", m.Begin)
+		} else {
+			position = fmt.Sprintf("(L.%d-%d)", m.Begin, m.End)
+		}
+	}
+	fmt.Fprintf(
+		w,
+		"%s:\n  %s\n",
+		levelColor[m.Level].Sprintf("%s %s", m.Level, position),
+		m.Text,
+	)
+	for _, d := range m.Details {
+		fmt.Fprintf(w, "  %s\n", d)
+	}
+}
+
+// Changes are the accepted fixes, to apply on the PKGBUILD.
+type Changes struct {
+	Options []pformat.FormatOption
+	Add     info.NodeInfoList
+	Remove  []int
+	Update  []Update
+}
+
+// Update is the new value of a node.
+type Update struct {
+	Id    int
+	Value string
+}
+
+// Apply applies the changes on the PKGBUILD.
+// The format options are not applied: they should be
+// given to the formater by the caller.
+func (c *Changes) Apply(p *pkgbuild.PKGBUILD) {
+	for _, u := range c.Update {
+		p.UpdateValue(u.Id, u.Value)
+	}
+	p.Add(c.Add...)
+	p.Remove(c.Remove...)
+}
+
+// Options are the options of the checks.
+type Options struct {
+	// Dir is the directory of the PKGBUILD,
+	// used to check the existence of the install file.
+	Dir string
+
+	// Exceptions are the depends which should not be checked.
+	// If nil, the exceptions file of the configuration is used.
+	Exceptions collection.Set[string]
+
+	// InKCP checks if a depend is provided by KCP.
+	// If nil, the depend is searched in the database of kcp.
+	InKCP func(string) bool
+}
+
+// Report is the result of the checks.
+type Report struct {
+	Messages []Message
+	Changes  *Changes
+}
+
+// Count returns the number of messages of the given level.
+func (r Report) Count(level Level) (count int) {
+	for _, m := range r.Messages {
+		if m.Level == level {
+			count++
+		}
+	}
+	return
+}
+
+// HasErrors returns true if at least one message is an error.
+func (r Report) HasErrors() bool {
+	return r.Count(Error) > 0
+}
+
+// Summary returns the number of errors and warnings in a human-readable format.
+func (r Report) Summary() string {
+	return common.Tr(msgSummary, r.Count(Error), r.Count(Warning))
+}
+
+// Print writes all messages of the given level or above.
+func (r Report) Print(w io.Writer, level Level) {
+	for _, m := range r.Messages {
+		if m.Level >= level {
+			m.Print(w)
+		}
+	}
+}
+
+// Checker is a check of the PKGBUILD.
+type Checker struct {
+	Name  string
+	Check func(c *Context)
+}
+
+// Context is the state of a check. Checkers use it
+// to read the PKGBUILD and to add messages.
+type Context struct {
+	*pkgbuild.PKGBUILD
+	Options
+	report *Report
+}
+
+// Add adds a message to the report.
+func (c *Context) Add(m Message) {
+	c.report.Messages = append(c.report.Messages, m)
+}
+
+// Changes returns the changes of the report, which can be modified by the fixes.
+func (c *Context) Changes() *Changes {
+	return c.report.Changes
+}
+
+func (c *Context) message(level Level, begin, end int, text string, fix *Fix, details ...string) {
+	c.Add(Message{
+		Level:   level,
+		Begin:   begin,
+		End:     end,
+		Text:    text,
+		Details: details,
+		Fix:     fix,
+	})
+}
+
+// Checkers is the list of the checks, in the order they are run.
+var Checkers = []Checker{
+	{"header", checkHeader},
+	{"duplicates", checkDuplicates},
+	{"missing-vars", checkMissingVars},
+	{"missing-funcs", checkMissingFuncs},
+	{"types", checkInfoTypes},
+	{"empty", checkEmpty},
+	{"pkgrel", checkPkgrel},
+	{"arch", checkArch},
+	{"depends", checkDepends},
+	{"install", checkInstall},
+	{"audit", checkAudit},
+}
+
+// Run runs the given checkers (all checkers if none is given) on the PKGBUILD.
+func Run(p *pkgbuild.PKGBUILD, options Options, checkers ...Checker) Report {
+	if len(checkers) == 0 {
+		checkers = Checkers
+	}
+	if options.Exceptions == nil {
+		options.Exceptions = LoadExceptions()
+	}
+	if options.InKCP == nil {
+		options.InKCP = searchKCP
+	}
+
+	report := Report{Changes: new(Changes)}
+	c := &Context{
+		PKGBUILD: p,
+		Options:  options,
+		report:   &report,
+	}
+	for _, checker := range checkers {
+		checker.Check(c)
+	}
+
+	return report
+}
+
+// RunFile decodes the PKGBUILD of the given directory and checks it.
+// If the PKGBUILD cannot be decoded (for example because it contains
+// commands at global scope), the error is returned with a report
+// containing the security audit of the file.
+func RunFile(dir string, options Options, checkers ...Checker) (p *pkgbuild.PKGBUILD, report Report, err error) {
+	var b []byte
+	if b, err = os.ReadFile(common.JoinIfRelative(dir, "PKGBUILD")); err != nil {
+		return
+	}
+	if p, err = pkgbuild.Decode(bytes.NewReader(b)); err != nil {
+		report = Report{Changes: new(Changes)}
+		// The file is parsed again since Decode doesn't return the syntax
+		// tree on error: it rejects valid shell scripts (like commands at
+		// global scope) which are precisely the ones to audit.
+		if findings, e := audit.Parse(bytes.NewReader(b)); e == nil {
+			c := &Context{report: &report}
+			c.addFindings(findings)
+		}
+		return
+	}
+	options.Dir = dir
+	report = Run(p, options, checkers...)
+	return
+}
+
+// LoadExceptions returns the depends listed in the exceptions file of the configuration.
+func LoadExceptions() (exceptions collection.Set[string]) {
+	exceptions = collection.NewSet[string]()
+	fp := common.Config.Get("pckcp.exceptionsFile")
+	if fp == "" {
+		return
+	}
+	fp = common.JoinIfRelative(common.ConfigBaseDir, fp)
+	f, err := os.Open(fp)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		for e := range strings.FieldsSeq(sc.Text()) {
+			exceptions.Add(e)
+		}
+	}
+	return
+}
+
+// kcpDatabase returns the database of kcp. It is read once, at the first call,
+// without migrating nor recovering it, since it belongs to kcp.
+var kcpDatabase = sync.OnceValue(func() (db database.Database) {
+	db = database.New()
+	f, err := os.Open(common.JoinIfRelative(common.UserBaseDir, common.Config.Get("kcp.dbFile")))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	db.Decode(f)
+	return
+})
+
+// searchKCP checks if a package of the kcp database provides the depend.
+func searchKCP(v string) bool {
+	_, ok := kcpDatabase().Provider(v)
+	return ok
+}
@@ -0,0 +1,377 @@
+package check
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"codeberg.org/bvaudour/kcp/common"
+	"codeberg.org/bvaudour/kcp/pkgbuild/audit"
+	pformat "codeberg.org/bvaudour/kcp/pkgbuild/format"
+	"codeberg.org/bvaudour/kcp/pkgbuild/info"
+	"codeberg.org/bvaudour/kcp/pkgbuild/standard"
+	"git.kaosx.ovh/benjamin/collection"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+const (
+	urlHook = "https://archlinux.org/pacman/alpm-hooks.5.html"
+)
+
+func lines(node *info.NodeInfo, inner bool) (int, int) {
+	begin, end := node.Position()
+	if inner {
+		begin, end = node.InnerPosition()
+	}
+	return int(begin.Line()), int(end.Line())
+}
+
+func formatPackage(v string) string {
+	for _, sep := range []string{">", "<", "=", ":"} {
+		i := strings.Index(v, sep)
+		if i >= 0 {
+			v = v[:i]
+		}
+	}
+	return v
+}
+
+func checkHeader(c *Context) {
+	if !c.HasHeader() {
+		c.message(Info, 0, 0, common.Tr(infoHeader), nil)
+		return
+	}
+	begin, _ := c.NodeInfoList[0].Position()
+	end, _ := c.NodeInfoList[0].InnerPosition()
+	c.message(Warning, int(begin.Line()), int(end.Line()-1), common.Tr(warnHeader), &Fix{
+		Question: common.Tr(questionHeader),
+		Default:  true,
+		Apply: func(string) {
+			c.Changes().Options = append(c.Changes().Options, pformat.OptionRemoveHeader)
+		},
+	})
+}
+
+func checkDuplicates(c *Context) {
+	duplicates := c.GetDuplicates()
+	if len(duplicates) == 0 {
+		c.message(Info, 0, 0, common.Tr(infoDuplicate), nil)
+		return
+	}
+
+	names := make([]string, 0, len(duplicates))
+	for name := range duplicates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	details := make([]string, len(names))
+	for i, name := range names {
+		nodes := duplicates[name]
+		positions := make([]string, len(nodes))
+		for j, node := range nodes {
+			begin, end := lines(node, true)
+			t := "V"
+			if node.Type == info.Function {
+				t = "F"
+			}
+			if begin == end {
+				positions[j] = fmt.Sprintf("L.%d (%s)", begin, t)
+			} else {
+				positions[j] = fmt.Sprintf("L.%d-%d (%s)", begin, end, t)
+			}
+		}
+		details[i] = fmt.Sprintf("- '%s': %s", name, strings.Join(positions, ", "))
+	}
+
+	c.message(Warning, 0, 0, common.Tr(warnDuplicate), &Fix{
+		Question: common.Tr(questionDuplicate),
+		Default:  true,
+		Apply: func(string) {
+			c.Changes().Options = append(c.Changes().Options, pformat.OptionRemoveDuplicates)
+		},
+	}, details...)
+}
+
+// newVariable parses the value typed by the user
+// and returns the node of the new variable.
+func (c *Context) newVariable(name, value string) (*info.NodeInfo, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return nil, false
+	}
+	parsed, err := syntax.NewParser().Parse(strings.NewReader(value), "")
+	if err != nil || len(parsed.Stmts) == 0 {
+		return nil, false
+	}
+	expr, ok := parsed.Stmts[0].Cmd.(*syntax.CallExpr)
+	if !ok || len(expr.Args) == 0 {
+		return nil, false
+	}
+	var line string
+	if standard.IsArrayVariable(name) || len(expr.Args) > 1 {
+		line = fmt.Sprintf("%s=(%s)", name, value)
+	} else {
+		line = fmt.Sprintf("%s=%s", name, value)
+	}
+	parsed, err = syntax.NewParser().Parse(strings.NewReader(line), "")
+	if err != nil || len(parsed.Stmts) == 0 {
+		return nil, false
+	}
+	node, err := info.New(0, parsed.Stmts[0], c.Env())
+	return node, err == nil
+}
+
+func checkMissingVars(c *Context) {
+	missings, missingChecksum := c.GetMissingVariables()
+	if missingChecksum && c.HasVariable(standard.SOURCE) {
+		c.message(Warning, 0, 0, common.Tr(errMissingChecksum), nil, common.Tr(commentAddManually))
+	} else {
+		c.message(Info, 0, 0, common.Tr(infoMissingChecksum), nil)
+	}
+	if len(missings) == 0 {
+		c.message(Info, 0, 0, common.Tr(infoMissingVar), nil)
+		return
+	}
+
+	for _, v := range missings {
+		c.message(Error, 0, 0, common.Tr(errMissingVar, v), &Fix{
+			Question: common.Tr(questionMissingVar, v),
+			Default:  true,
+			Prompt:   common.Tr(questionAddValue, v),
+			Apply: func(value string) {
+				if node, ok := c.newVariable(v, value); ok {
+					c.Changes().Add = append(c.Changes().Add, node)
+				}
+			},
+		})
+	}
+}
+
+func checkMissingFuncs(c *Context) {
+	missings := c.GetMissingFunctions()
+	if len(missings) == 0 {
+		c.message(Info, 0, 0, common.Tr(infoMissingFunc), nil)
+		return
+	}
+
+	for _, f := range missings {
+		c.message(Error, 0, 0, common.Tr(errMissingFunc, f), nil, common.Tr(commentAddManually))
+	}
+}
+
+func typeComment(t info.NodeType) string {
+	switch t {
+	case info.Function:
+		return common.Tr(commentFunction)
+	case info.ArrayVar:
+		return common.Tr(commentArrayVar)
+	}
+	return common.Tr(commentStringVar)
+}
+
+func checkInfoTypes(c *Context) {
+	clean := true
+	for _, node := range c.NodeInfoList {
+		name := node.Name
+		actualType, neededType := node.Type, node.Type
+		if standard.IsStandardFunction(name) {
+			neededType = info.Function
+		} else if standard.IsArrayVariable(name) {
+			neededType = info.ArrayVar
+		} else if standard.IsStandardVariable(name) {
+			neededType = info.SingleVar
+		}
+		if actualType == neededType {
+			continue
+		}
+		clean = false
+		begin, end := lines(node, false)
+		c.message(
+			Warning,
+			begin,
+			end,
+			common.Tr(warnBadType, name, typeComment(actualType), typeComment(neededType)),
+			nil,
+		)
+	}
+
+	if clean {
+		c.message(Info, 0, 0, common.Tr(infoBadType), nil)
+	}
+}
+
+func checkEmpty(c *Context) {
+	clean := true
+	for _, node := range c.NodeInfoList {
+		if node.Type == info.Function {
+			continue
+		}
+		if node.Value == "" && len(node.Values) == 0 {
+			clean = false
+			begin, end := lines(node, true)
+			id := node.Id
+			c.message(Warning, begin, end, common.Tr(warnEmpty, node.Name), &Fix{
+				Question: common.Tr(questionRemoveEmpty, node.Name),
+				Default:  true,
+				Apply: func(string) {
+					c.Changes().Remove = append(c.Changes().Remove, id)
+				},
+			})
+		}
+	}
+
+	if clean {
+		c.message(Info, 0, 0, common.Tr(infoEmpty), nil)
+	}
+}
+
+func checkPkgrel(c *Context) {
+	n, i := c.FindLast(standard.PKGREL, info.SingleVar)
+	if i < 0 {
+		return
+	}
+	begin, end := lines(n, true)
+	if n.Value == "1" {
+		c.message(Info, begin, end, common.Tr(infoVarClean, standard.PKGREL), nil)
+		return
+	}
+	c.message(Warning, begin, end, common.Tr(warnPkgrel), &Fix{
+		Question: common.Tr(questionPkgrel),
+		Default:  false,
+		Apply: func(string) {
+			c.Changes().Update = append(c.Changes().Update, Update{n.Id, "1"})
+		},
+	})
+}
+
+func checkArch(c *Context) {
+	n, i := c.FindLast(standard.ARCH, info.ArrayVar)
+	if i < 0 {
+		return
+	}
+	begin, end := lines(n, true)
+	if v := n.Values; len(v) == 1 && v[0] == "x86_64" {
+		c.message(Info, begin, end, common.Tr(infoVarClean, standard.ARCH), nil)
+		return
+	}
+	c.message(Warning, begin, end, common.Tr(warnArch), &Fix{
+		Question: common.Tr(questionArch),
+		Default:  true,
+		Apply: func(string) {
+			c.Changes().Update = append(c.Changes().Update, Update{n.Id, "'x86_64'"})
+		},
+	})
+}
+
+func (c *Context) isPackageInRepo(v string) bool {
+	return common.IsInRepo(v) || c.InKCP(v)
+}
+
+func (c *Context) checkDepend(node *info.NodeInfo, pkgname string) {
+	errors := make(map[string]int)
+	values := make([]string, len(node.Values))
+	copy(values, node.Values)
+
+	for i, v := range values {
+		v = formatPackage(v)
+		values[i] = v
+		if v == pkgname {
+			errors[v] = 1
+		} else if !c.Exceptions.Contains(v) && !c.isPackageInRepo(v) {
+			errors[v] = 2
+		}
+	}
+	name := node.Name
+	begin, end := lines(node, true)
+	if len(errors) == 0 {
+		c.message(Info, begin, end, common.Tr(infoVarClean, name), nil)
+		return
+	}
+
+	var details []string
+	for _, v := range values {
+		if t, ok := errors[v]; ok {
+			m := warnPackageNotInRepo
+			if t == 1 {
+				m = warnPackageIsName
+			}
+			details = append(details, common.Tr(m, v))
+		}
+	}
+	c.message(Warning, begin, end, common.Tr(warnDepends, name), nil, details...)
+}
+
+func checkDepends(c *Context) {
+	var hasDepend bool
+	pkgname := c.GetValue(standard.PKGNAME)
+	names := collection.NewSet(
+		standard.CONFLICTS,
+		standard.PROVIDES,
+		standard.REPLACES,
+		standard.DEPENDS,
+		standard.MAKEDEPENDS,
+		standard.OPTDEPENDS,
+		standard.CHECKDEPENDS,
+	)
+	nameDepends := collection.NewSet(
+		standard.DEPENDS,
+		standard.MAKEDEPENDS,
+	)
+
+	for _, node := range c.NodeInfoList {
+		name := node.Name
+		if node.Type != info.ArrayVar || !names.Contains(name) {
+			continue
+		}
+		hasDepend = hasDepend || nameDepends.Contains(name)
+		c.checkDepend(node, pkgname)
+	}
+	if !hasDepend {
+		c.message(Warning, 0, 0, common.Tr(warnMissingDepends), nil)
+	}
+}
+
+func checkInstall(c *Context) {
+	node, index := c.FindLast(standard.INSTALL, info.SingleVar)
+	if index < 0 {
+		return
+	}
+	begin, end := lines(node, true)
+	if common.FileExists(filepath.Join(c.Dir, node.Value)) {
+		c.message(Info, begin, end, common.Tr(infoVarClean, standard.INSTALL), nil, common.Tr(commentInstall, urlHook))
+		return
+	}
+	c.message(Warning, begin, end, common.Tr(warnInstall, node.Value), &Fix{
+		Question: common.Tr(questionInstall, node.Value),
+		Default:  true,
+		Prompt:   common.Tr(questionInstall2),
+		Apply: func(value string) {
+			if value == "" {
+				c.Changes().Remove = append(c.Changes().Remove, node.Id)
+			} else {
+				c.Changes().Update = append(c.Changes().Update, Update{node.Id, value})
+			}
+		},
+	})
+}
+
+func (c *Context) addFindings(findings audit.Findings) {
+	for _, f := range findings {
+		level := Warning
+		if f.Severity == audit.Dangerous {
+			level = Error
+		}
+		c.message(level, f.Line, f.Line, f.Text, nil, fmt.Sprintf("%s: %s", f.Scope(), f.Code))
+	}
+}
+
+func checkAudit(c *Context) {
+	findings := audit.Analyze(c.Stmts())
+	if len(findings) == 0 {
+		c.message(Info, 0, 0, common.Tr(infoAudit), nil)
+		return
+	}
+	c.addFindings(findings)
+}
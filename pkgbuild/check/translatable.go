@@ -0,0 +1,52 @@
+package check
+
+// Messages’ templates
+const (
+	typeError   = "Error"
+	typeWarning = "Warning"
+	typeInfo    = "Info"
+
+	errMissingChecksum = "Checksum is missing."
+	errMissingVar      = "Variable '%s' is missing."
+	errMissingFunc     = "Function '%s' is missing."
+
+	warnHeader           = "Header was found. Do not use names of maintainers or contributors in PKGBUILD, anyone can contribute, keep the header clean from this."
+	warnDuplicate        = "Some duplicates found:"
+	warnBadType          = "Bad type declaration: '%s' is %s but it should be %s."
+	warnEmpty            = "Variable '%s' is empty."
+	warnPkgrel           = "pkgrel is different from 1. It should be the case only if build instructions are edited but not pkgver."
+	warnArch             = "arch is different from 'x86_64'. Since KaOS only supports this architecture, no other arch would be added here."
+	warnInstall          = "install: file '%s' doesn’t exist."
+	warnDepends          = "Variable '%s' contains bad packages."
+	warnPackageIsName    = "'%s' is the name of the package. It is useless."
+	warnPackageNotInRepo = "'%s' isn't in repo neither in kcp."
+	warnMissingDepends   = "Variables 'depends' and 'makedepends' are empty. You should manually check if it is not a missing."
+
+	infoHeader          = "Header is clean."
+	infoDuplicate       = "There aren’t duplicates."
+	infoMissingChecksum = "Checksum is OK."
+	infoMissingVar      = "There aren’t missing variables."
+	infoMissingFunc     = "There aren’t missing functions."
+	infoBadType         = "Declarations have the good type."
+	infoEmpty           = "There aren’t empty variables."
+	infoVarClean        = "Variable '%s' is clean."
+	infoAudit           = "No risky construct found."
+
+	questionHeader      = "Remove header?"
+	questionDuplicate   = "Remove duplicates?"
+	questionMissingVar  = "Add variable '%s'?"
+	questionAddValue    = "Set variable '%s' with (leave blank to ignore):"
+	questionRemoveEmpty = "Remove variable '%s'?"
+	questionPkgrel      = "Reset pkgrel to 1?"
+	questionArch        = "Reset arch to x86_64?"
+	questionInstall     = "Modify name of '%s' file?"
+	questionInstall2    = "Type the new name (leave blank to remove install variable):"
+
+	commentAddManually = "You should add it manually."
+	commentFunction    = "a function"
+	commentStringVar   = "a string variable"
+	commentArrayVar    = "an array variable"
+	commentInstall     = "Note that hooks provide similar functionnalities and are more powerful. For more informations: %s"
+
+	msgSummary = "%d error(s), %d warning(s)"
+)
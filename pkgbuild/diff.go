@@ -0,0 +1,383 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"codeberg.org/bvaudour/kcp/common"
+	"codeberg.org/bvaudour/kcp/pkgbuild/info"
+	"codeberg.org/bvaudour/kcp/pkgbuild/position"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// ChangeType represents the kind of a change between two PKGBUILDs.
+type ChangeType uint
+
+const (
+	Added ChangeType = iota
+	Removed
+	Changed
+	CommentChanged
+)
+
+// Change represents a difference between two PKGBUILDs
+// on a declaration (variable or function).
+// If the name is empty, the change concerns the comments
+// at the end of the file.
+type Change struct {
+	Type     ChangeType
+	Name     string
+	Old, New *info.NodeInfo
+
+	// Added and Removed are the elements added to or removed
+	// from an array variable.
+	Added, Removed []string
+
+	// Diff is the unified diff of the body of a changed function.
+	Diff string
+}
+
+// IsFunction returns true if the change concerns a function.
+func (c Change) IsFunction() bool {
+	node := c.New
+	if node == nil {
+		node = c.Old
+	}
+	return node != nil && node.Type == info.Function
+}
+
+func nodeValue(node *info.NodeInfo) string {
+	if node.Type == info.ArrayVar {
+		return fmt.Sprintf("(%s)", strings.Join(node.Values, " "))
+	}
+	return node.Value
+}
+
+// String returns a one-line summary of the change.
+func (c Change) String() string {
+	switch {
+	case c.Type == CommentChanged && c.Name == "":
+		return common.Tr(diffLastComments)
+	case c.Type == CommentChanged:
+		return common.Tr(diffComments, c.Name)
+	case c.IsFunction() && c.Type == Added:
+		return common.Tr(diffAddedFunc, c.Name)
+	case c.IsFunction() && c.Type == Removed:
+		return common.Tr(diffRemovedFunc, c.Name)
+	case c.IsFunction():
+		return common.Tr(diffChangedFunc, c.Name)
+	case c.Type == Added:
+		return common.Tr(diffAddedVar, c.Name, nodeValue(c.New))
+	case c.Type == Removed:
+		return common.Tr(diffRemovedVar, c.Name)
+	case c.Old.Type != info.ArrayVar || c.New.Type != info.ArrayVar:
+		return common.Tr(diffChangedVar, c.Name, nodeValue(c.Old), nodeValue(c.New))
+	}
+
+	var details []string
+	if len(c.Added) > 0 {
+		details = append(details, common.Tr(diffAddedValues, strings.Join(c.Added, ", ")))
+	}
+	if len(c.Removed) > 0 {
+		details = append(details, common.Tr(diffRemovedValues, strings.Join(c.Removed, ", ")))
+	}
+	if len(details) == 0 {
+		details = append(details, common.Tr(diffReordered))
+	}
+	return common.Tr(diffChangedArray, c.Name, strings.Join(details, ", "))
+}
+
+// Changes is a list of changes.
+type Changes []Change
+
+// Split separates the changes of code from the comment-only changes.
+func (changes Changes) Split() (code, comments Changes) {
+	for _, c := range changes {
+		if c.Type == CommentChanged {
+			comments = append(comments, c)
+		} else {
+			code = append(code, c)
+		}
+	}
+	return
+}
+
+// Print writes the changes to the given writer,
+// with the diffs of the changed functions.
+// The comment-only changes are written at the end.
+func (changes Changes) Print(w io.Writer) {
+	code, comments := changes.Split()
+	for _, c := range code {
+		fmt.Fprintf(w, "- %s\n", c)
+		if c.Diff != "" {
+			for _, l := range strings.Split(strings.TrimSuffix(c.Diff, "\n"), "\n") {
+				fmt.Fprintf(w, "    %s\n", l)
+			}
+		}
+	}
+	if len(comments) == 0 {
+		return
+	}
+	fmt.Fprintln(w, common.Tr(diffCommentsOnly))
+	for _, c := range comments {
+		fmt.Fprintf(w, "- %s\n", c)
+	}
+}
+
+// lastNodes returns the last declaration of each name
+// and the names in order of declaration.
+func lastNodes(infos info.NodeInfoList) (nodes map[string]*info.NodeInfo, names []string) {
+	nodes = make(map[string]*info.NodeInfo)
+	for _, node := range infos {
+		if _, ok := nodes[node.Name]; !ok {
+			names = append(names, node.Name)
+		}
+		nodes[node.Name] = node
+	}
+	return
+}
+
+func commentTexts(comments []syntax.Comment) (texts []string) {
+	for _, c := range comments {
+		texts = append(texts, strings.TrimSpace(c.Text))
+	}
+	return
+}
+
+// nodeComments returns the texts of all comments of the node.
+func nodeComments(node *info.NodeInfo) []string {
+	_, end := node.InnerPosition()
+	var trailing []syntax.Comment
+	for _, c := range node.Stmt.Comments {
+		if position.Cmp(c.Pos(), end) >= 0 {
+			trailing = append(trailing, c)
+		}
+	}
+	return commentTexts(slices.Concat(node.HeaderComments(), node.InlineComments(), trailing))
+}
+
+// stripComments returns the body of a function without its comments.
+func stripComments(body string) string {
+	file, err := syntax.NewParser(syntax.KeepComments(false)).Parse(strings.NewReader("f() "+body), "")
+	if err != nil || len(file.Stmts) != 1 {
+		return body
+	}
+	fn, ok := file.Stmts[0].Cmd.(*syntax.FuncDecl)
+	if !ok {
+		return body
+	}
+	var sb strings.Builder
+	if err = syntax.NewPrinter().Print(&sb, fn.Body); err != nil {
+		return body
+	}
+	return sb.String()
+}
+
+// countValues returns the number of occurrences of each value.
+func countValues(values []string) map[string]int {
+	count := make(map[string]int)
+	for _, v := range values {
+		count[v]++
+	}
+	return count
+}
+
+// diffValues returns the elements added and removed between two arrays.
+func diffValues(oldValues, newValues []string) (added, removed []string) {
+	oldCount, newCount := countValues(oldValues), countValues(newValues)
+	for _, v := range newValues {
+		if oldCount[v] > 0 {
+			oldCount[v]--
+		} else {
+			added = append(added, v)
+		}
+	}
+	for _, v := range oldValues {
+		if newCount[v] > 0 {
+			newCount[v]--
+		} else {
+			removed = append(removed, v)
+		}
+	}
+	return
+}
+
+func diffNode(name string, oldNode, newNode *info.NodeInfo) (changes Changes) {
+	switch {
+	case oldNode == nil:
+		return Changes{{Type: Added, Name: name, New: newNode}}
+	case newNode == nil:
+		return Changes{{Type: Removed, Name: name, Old: oldNode}}
+	case (oldNode.Type == info.Function) != (newNode.Type == info.Function):
+		return Changes{
+			{Type: Removed, Name: name, Old: oldNode},
+			{Type: Added, Name: name, New: newNode},
+		}
+	}
+
+	c := Change{Type: Changed, Name: name, Old: oldNode, New: newNode}
+	switch {
+	case newNode.Type == info.Function:
+		if stripComments(oldNode.Value) != stripComments(newNode.Value) {
+			c.Diff = unifiedDiff(oldNode.Value, newNode.Value)
+			return Changes{c}
+		}
+	case oldNode.Type == info.ArrayVar && newNode.Type == info.ArrayVar:
+		if !slices.Equal(oldNode.Values, newNode.Values) {
+			c.Added, c.Removed = diffValues(oldNode.Values, newNode.Values)
+			return Changes{c}
+		}
+	case oldNode.Type != newNode.Type || oldNode.Value != newNode.Value:
+		return Changes{c}
+	}
+
+	if oldNode.Value != newNode.Value || !slices.Equal(nodeComments(oldNode), nodeComments(newNode)) {
+		c.Type = CommentChanged
+		return Changes{c}
+	}
+	return
+}
+
+// Diff compares semantically two PKGBUILDs and returns
+// the changes between them: added, removed or changed variables
+// and functions, and the declarations which only differ
+// by their comments.
+// Variables are compared by their values, and functions
+// by their bodies, so formatting changes are ignored.
+// If a name is declared several times, only the last declaration
+// is compared.
+func Diff(oldP, newP *PKGBUILD) (changes Changes) {
+	oldNodes, oldNames := lastNodes(oldP.NodeInfoList)
+	newNodes, newNames := lastNodes(newP.NodeInfoList)
+
+	for _, name := range newNames {
+		changes = append(changes, diffNode(name, oldNodes[name], newNodes[name])...)
+	}
+	for _, name := range oldNames {
+		if _, ok := newNodes[name]; !ok {
+			changes = append(changes, diffNode(name, oldNodes[name], nil)...)
+		}
+	}
+
+	var oldLast, newLast []syntax.Comment
+	if oldP.file != nil {
+		oldLast = oldP.file.Last
+	}
+	if newP.file != nil {
+		newLast = newP.file.Last
+	}
+	if !slices.Equal(commentTexts(oldLast), commentTexts(newLast)) {
+		changes = append(changes, Change{Type: CommentChanged})
+	}
+
+	return
+}
+
+const diffContext = 3
+
+type diffLine struct {
+	op       byte
+	text     string
+	old, new int
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns the edit script between two lists of lines.
+// Each line is prefixed by ' ' (unchanged), '-' (removed) or '+' (added)
+// and keeps the number of old and new lines which precede it.
+func diffLines(a, b []string) (lines []diffLine) {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+	return
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// unifiedDiff returns the hunks of the unified diff between two texts.
+func unifiedDiff(a, b string) string {
+	lines := diffLines(splitLines(a), splitLines(b))
+	var sb strings.Builder
+
+	for i := 0; i < len(lines); {
+		for i < len(lines) && lines[i].op == ' ' {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+		end := i
+		for j := i; j < len(lines) && j-end <= 2*diffContext; j++ {
+			if lines[j].op != ' ' {
+				end = j
+			}
+		}
+		start, stop := max(0, i-diffContext), min(len(lines), end+diffContext+1)
+
+		var oldCount, newCount int
+		for _, l := range lines[start:stop] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(
+			&sb,
+			"@@ -%s +%s @@\n",
+			hunkRange(lines[start].old, oldCount),
+			hunkRange(lines[start].new, newCount),
+		)
+		for _, l := range lines[start:stop] {
+			fmt.Fprintf(&sb, "%c%s\n", l.op, l.text)
+		}
+		i = stop
+	}
+
+	return sb.String()
+}
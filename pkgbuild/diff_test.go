@@ -0,0 +1,140 @@
+package pkgbuild
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type change struct {
+		typ            ChangeType
+		name           string
+		added, removed []string
+	}
+
+	tests := []struct {
+		name     string
+		old, new string
+		changes  []change
+	}{
+		{
+			"identical",
+			"pkgname=foo\ndepends=('a' 'b')\nbuild() {\n  make\n}\n",
+			"pkgname=foo\ndepends=('a' 'b')\nbuild() {\n  make\n}\n",
+			nil,
+		},
+		{
+			"added and removed",
+			"pkgname=foo\n_commit=abc\n",
+			"pkgname=foo\nprepare() {\n  patch -p1 < fix.patch\n}\n",
+			[]change{{Added, "prepare", nil, nil}, {Removed, "_commit", nil, nil}},
+		},
+		{
+			"changed variable",
+			"pkgname=foo\npkgver=1.0\n",
+			"pkgname=foo\npkgver=1.1\n",
+			[]change{{Changed, "pkgver", nil, nil}},
+		},
+		{
+			"changed array",
+			"depends=('a' 'b' 'c')\n",
+			"depends=('c' 'd' 'a')\n",
+			[]change{{Changed, "depends", []string{"d"}, []string{"b"}}},
+		},
+		{
+			"reordered array",
+			"depends=('a' 'b')\n",
+			"depends=('b' 'a')\n",
+			[]change{{Changed, "depends", nil, nil}},
+		},
+		{
+			"changed function",
+			"build() {\n  make\n}\n",
+			"build() {\n  make -j2\n}\n",
+			[]change{{Changed, "build", nil, nil}},
+		},
+		{
+			"variable replaced by a function",
+			"check=true\n",
+			"check() {\n  make test\n}\n",
+			[]change{{Removed, "check", nil, nil}, {Added, "check", nil, nil}},
+		},
+		{
+			"last declaration",
+			"pkgver=1.0\npkgver=2.0\n",
+			"pkgver=2.0\n",
+			nil,
+		},
+		{
+			"comments",
+			"# Maintainer: foo\npkgname=foo\nbuild() {\n  # configure\n  make\n}\n",
+			"# Maintainer: bar\npkgname=foo\nbuild() {\n  # build\n  make\n}\n# end\n",
+			[]change{{CommentChanged, "pkgname", nil, nil}, {CommentChanged, "build", nil, nil}, {CommentChanged, "", nil, nil}},
+		},
+	}
+
+	for _, tt := range tests {
+		oldP, err := Decode(strings.NewReader(tt.old))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		newP, err := Decode(strings.NewReader(tt.new))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		changes := Diff(oldP, newP)
+		var got []change
+		for _, c := range changes {
+			got = append(got, change{c.Type, c.Name, c.Added, c.Removed})
+		}
+		if !slices.EqualFunc(got, tt.changes, func(c1, c2 change) bool {
+			return c1.typ == c2.typ && c1.name == c2.name && slices.Equal(c1.added, c2.added) && slices.Equal(c1.removed, c2.removed)
+		}) {
+			t.Errorf("%s: changes %+v, want %+v", tt.name, got, tt.changes)
+		}
+	}
+}
+
+func TestDiffFunction(t *testing.T) {
+	oldP, _ := Decode(strings.NewReader("build() {\n  cd foo\n  make\n}\n"))
+	newP, _ := Decode(strings.NewReader("build() {\n  cd foo\n  make -j2\n}\n"))
+	changes := Diff(oldP, newP)
+	if len(changes) != 1 || !changes[0].IsFunction() {
+		t.Fatalf("changes: %+v", changes)
+	}
+	if want := "@@ -1,4 +1,4 @@\n {\n \tcd foo\n-\tmake\n+\tmake -j2\n }\n"; changes[0].Diff != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", changes[0].Diff, want)
+	}
+
+	code, comments := changes.Split()
+	if len(code) != 1 || len(comments) != 0 {
+		t.Errorf("split: %v, %v", code, comments)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a, b, diff string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"", "a\n", "@@ -0,0 +1 @@\n+a\n"},
+		{"a\n", "", "@@ -1 +0,0 @@\n-a\n"},
+		{
+			"a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			"a\nB\nc\nd\ne\nf\ng\nh\ni\nJ\n",
+			"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n@@ -7,4 +7,4 @@\n g\n h\n i\n-j\n+J\n",
+		},
+		{
+			"a\nb\nc\nd\ne\nf\n",
+			"a\nB\nc\nd\ne\nF\n",
+			"@@ -1,6 +1,6 @@\n a\n-b\n+B\n c\n d\n e\n-f\n+F\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := unifiedDiff(tt.a, tt.b); diff != tt.diff {
+			t.Errorf("unifiedDiff(%q, %q) = %q, want %q", tt.a, tt.b, diff, tt.diff)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package pkgbuild
+
+// Messages’ templates
+const (
+	diffAddedVar      = "New variable '%s': %s"
+	diffRemovedVar    = "Variable '%s' removed"
+	diffChangedVar    = "Variable '%s' changed: %s → %s"
+	diffChangedArray  = "Variable '%s' changed: %s"
+	diffAddedValues   = "new %s"
+	diffRemovedValues = "removed %s"
+	diffReordered     = "reordered"
+	diffAddedFunc     = "New function %s()"
+	diffRemovedFunc   = "Function %s() removed"
+	diffChangedFunc   = "Function %s() changed"
+	diffComments      = "Comments of '%s' changed"
+	diffLastComments  = "Comments at the end of the file changed"
+	diffCommentsOnly  = "Comment-only changes:"
+)